@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/simon-fredrich/function-gitlab-importer/internal/guard"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// adoptionKey identifies an external resource. Versions of a kind share
+// their external resources, so only the group and kind are kept.
+type adoptionKey struct {
+	gk           schema.GroupKind
+	externalName string
+}
+
+// adoptions records which composed resource manages an external resource
+// within the current request, so no external resource is adopted twice.
+type adoptions map[adoptionKey]resource.Name
+
+// adopt records that the composed resource manages the external resource.
+func (a adoptions) adopt(gvk schema.GroupVersionKind, externalName string, name resource.Name) {
+	a[adoptionKey{gk: gvk.GroupKind(), externalName: externalName}] = name
+}
+
+// check blocks the adoption of an external resource managed by another
+// composed resource.
+func (a adoptions) check(gvk schema.GroupVersionKind, externalName string, name resource.Name) error {
+	other, ok := a[adoptionKey{gk: gvk.GroupKind(), externalName: externalName}]
+	if !ok || other == name {
+		return nil
+	}
+	return guard.Blocked("%s %s is already adopted by composed resource %q", gvk.Kind, externalName, other)
+}
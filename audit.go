@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+
+	"github.com/simon-fredrich/function-gitlab-importer/internal/audit"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/gitlabclient"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/request"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// recordAudit records the outcome of every processed resource with the audit
+// sink of the Function. The management policies of imported and already
+// managed resources are read from the desired resources, as they were sent
+// back to Crossplane. Other resources have not been touched by the Function,
+// so their policies are left out.
+func (f *Function) recordAudit(ctx context.Context, req *fnv1.RunFunctionRequest, desired map[resource.Name]*resource.DesiredComposed, outcomes []outcome) error {
+	oxr, err := request.GetObservedCompositeResource(req)
+	if err != nil {
+		return errors.Errorf("cannot get observed composite resource: %w", err)
+	}
+	composite := audit.Reference{
+		APIVersion: oxr.Resource.GetAPIVersion(),
+		Kind:       oxr.Resource.GetKind(),
+		Namespace:  oxr.Resource.GetNamespace(),
+		Name:       oxr.Resource.GetName(),
+		UID:        string(oxr.Resource.GetUID()),
+	}
+	identity := f.tokenIdentity(ctx)
+	now := f.now()
+
+	for _, o := range outcomes {
+		e := audit.Entry{
+			Time:          now,
+			Composite:     composite,
+			Resource:      string(o.name),
+			GVK:           o.gvk.String(),
+			Decision:      string(o.status),
+			Condition:     o.condition,
+			ChosenID:      o.result.ExternalName,
+			TokenIdentity: identity,
+		}
+		switch o.status {
+		case outcomeSkipped, outcomeIgnored:
+			e.Reason = o.reason
+		case outcomeBlocked, outcomePending:
+			// blocked and pending resources have been looked up, but their
+			// external-name has not been set
+			e.Reason = o.reason
+			e.ChosenID = ""
+		case outcomeFailed:
+			e.Reason = o.err.Error()
+		}
+		for _, c := range o.result.Candidates {
+			e.Candidates = append(e.Candidates, audit.Candidate{ID: c.ID, FullPath: c.FullPath})
+		}
+		if des, ok := desired[o.name]; ok && o.managed() {
+			e.ManagementPolicies, _ = des.Resource.GetStringArray("spec.managementPolicies")
+		}
+		if err := f.audit.Record(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenIdentity returns the GitLab user the token of the Function belongs to.
+// It is looked up as soon as the Function has a client and kept once the
+// lookup succeeded, failed lookups are retried by the next request. It is
+// empty if the Function has not talked to GitLab yet or the lookup failed.
+func (f *Function) tokenIdentity(ctx context.Context) string {
+	if f.Client == nil {
+		return ""
+	}
+	f.identity.Lock()
+	defer f.identity.Unlock()
+	if f.identity.name != "" {
+		return f.identity.name
+	}
+	name, err := gitlabclient.TokenIdentity(ctx, f.Client)
+	if err != nil {
+		f.log.Info("Cannot identify GitLab token", "err", err)
+		return ""
+	}
+	f.identity.name = name
+	return name
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/audit"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/testutils"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestRecordAudit(t *testing.T) {
+	observed, err := testutils.LoadDataFromFile("external-name-missing.json")
+	if err != nil {
+		t.Fatalf("cannot load data: %v", err)
+	}
+
+	// serve the token's user and the projects of namespace 1 like GitLab does
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v4/user":
+			_, _ = w.Write([]byte(`{"id": 7, "username": "importer-bot"}`))
+		case "/api/v4/groups/1/projects":
+			_, _ = w.Write([]byte(`[
+				{"id": 42, "path": "project-to-import", "path_with_namespace": "team/project-to-import"},
+				{"id": 43, "path": "project-to-import-old", "path_with_namespace": "team/project-to-import-old"}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client, err := gitlab.NewClient("token", gitlab.WithBaseURL(srv.URL+"/api/v4"))
+	if err != nil {
+		t.Fatalf("cannot create gitlab client: %v", err)
+	}
+
+	composite := resource.MustStructJSON(`{
+		"apiVersion": "gitlab.example.org/v1alpha1",
+		"kind": "SimpleProject",
+		"metadata": {"name": "xr", "uid": "1234"}
+	}`)
+	desired := resource.MustStructJSON(`{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"spec": {"forProvider": {"namespaceId": 1, "path": "project-to-import"}}
+	}`)
+	bucket := resource.MustStructJSON(`{"apiVersion": "s3.aws.upbound.io/v1beta1", "kind": "Bucket"}`)
+
+	// every entry is recorded for the same composite and composed resource
+	entry := func(decision, reason, chosenID string, policies ...string) audit.Entry {
+		return audit.Entry{
+			Time: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			Composite: audit.Reference{
+				APIVersion: "gitlab.example.org/v1alpha1",
+				Kind:       "SimpleProject",
+				Name:       "xr",
+				UID:        "1234",
+			},
+			Resource:  "project",
+			GVK:       "projects.gitlab.crossplane.io/v1alpha1, Kind=Project",
+			Decision:  decision,
+			Reason:    reason,
+			Condition: "create failed: cannot create Gitlab project: POST https://gitlab.com/api/v4/projects: 400 {message: {name: [has already been taken]}, {path: [has already been taken]}, {project_namespace.name: [has already been taken]}}",
+			Candidates: []audit.Candidate{
+				{ID: "42", FullPath: "team/project-to-import"},
+				{ID: "43", FullPath: "team/project-to-import-old"},
+			},
+			ChosenID:           chosenID,
+			ManagementPolicies: policies,
+			TokenIdentity:      "importer-bot (7)",
+		}
+	}
+
+	// a project managed by an earlier pipeline step, whose GitLab project
+	// does not exist
+	notExisting := resource.MustStructJSON(`{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"status": {"conditions": [{"type": "Synced", "status": "True", "reason": "ReconcileSuccess"}]}
+	}`)
+	managedByEarlierStep := resource.MustStructJSON(`{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"spec": {"forProvider": {"namespaceId": 1, "path": "project-to-import"}, "managementPolicies": ["*"]}
+	}`)
+	// a project imported by an earlier request
+	alreadyManaged := resource.MustStructJSON(`{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"metadata": {"annotations": {"crossplane.io/external-name": "42", "crossplane.io/managed-external-name": "true"}}
+	}`)
+
+	type args struct {
+		input    string
+		observed *structpb.Struct
+		desired  *structpb.Struct
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []audit.Entry
+	}{
+		"Imported": {
+			reason: "An imported resource should be recorded with the chosen GitLab ID and its management policies.",
+			args:   args{input: `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input"}`},
+			want:   []audit.Entry{entry("Imported", "", "42", "Observe")},
+		},
+		"Ignored": {
+			reason: "A resource the function does not handle should be recorded in explain mode.",
+			args:   args{input: `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "explain": true}`},
+			want: []audit.Entry{
+				{
+					Time:          time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+					Composite:     audit.Reference{APIVersion: "gitlab.example.org/v1alpha1", Kind: "SimpleProject", Name: "xr", UID: "1234"},
+					Resource:      "bucket",
+					GVK:           "s3.aws.upbound.io/v1beta1, Kind=Bucket",
+					Decision:      "Ignored",
+					Reason:        "kind is not handled by the function",
+					TokenIdentity: "importer-bot (7)",
+				},
+				entry("Imported", "", "42", "Observe"),
+			},
+		},
+		"AlreadyManaged": {
+			reason: "A resource imported before should be recorded with its external-name and management policies.",
+			args:   args{input: `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input"}`, observed: alreadyManaged},
+			want: []audit.Entry{
+				{
+					Time:               time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+					Composite:          audit.Reference{APIVersion: "gitlab.example.org/v1alpha1", Kind: "SimpleProject", Name: "xr", UID: "1234"},
+					Resource:           "project",
+					GVK:                "projects.gitlab.crossplane.io/v1alpha1, Kind=Project",
+					Decision:           "Skipped",
+					Reason:             "external-name 42 is already managed",
+					ChosenID:           "42",
+					ManagementPolicies: []string{"Observe"},
+					TokenIdentity:      "importer-bot (7)",
+				},
+			},
+		},
+		"NotTouched": {
+			reason: "A resource the function did not touch should be recorded without the management policies set by an earlier pipeline step.",
+			args:   args{input: `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input"}`, observed: notExisting, desired: managedByEarlierStep},
+			want: []audit.Entry{
+				{
+					Time:          time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+					Composite:     audit.Reference{APIVersion: "gitlab.example.org/v1alpha1", Kind: "SimpleProject", Name: "xr", UID: "1234"},
+					Resource:      "project",
+					GVK:           "projects.gitlab.crossplane.io/v1alpha1, Kind=Project",
+					Decision:      "Skipped",
+					Reason:        "no existing external resource reported",
+					TokenIdentity: "importer-bot (7)",
+				},
+			},
+		},
+		"WouldImport": {
+			reason: "A resource that would have been imported in dry run mode should be recorded with the chosen GitLab ID.",
+			args:   args{input: `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "dryRun": true}`},
+			want:   []audit.Entry{entry("WouldImport", "", "42")},
+		},
+		"Blocked": {
+			reason: "A blocked resource should be recorded with its reason, but without a chosen GitLab ID.",
+			args:   args{input: `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "importDeny": [{"fullPath": "team/*"}]}`},
+			want:   []audit.Entry{entry("Blocked", "team/project-to-import matches deny pattern fullPath=team/*", "")},
+		},
+		"Pending": {
+			reason: "A resource waiting for approval should be recorded with its reason, but without a chosen GitLab ID.",
+			args:   args{input: `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "requireApproval": true}`},
+			want: []audit.Entry{entry("Pending", "found 42 (team/project-to-import), approve its import by annotating the composite resource with "+
+				"gitlab-importer.fn.crossplane.io/approve-import: team/project-to-import", "")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if tc.args.observed == nil {
+				tc.args.observed = resource.MustStructJSON(string(observed))
+			}
+			if tc.args.desired == nil {
+				tc.args.desired = desired
+			}
+			req := &fnv1.RunFunctionRequest{
+				Input: resource.MustStructJSON(tc.args.input),
+				Observed: &fnv1.State{
+					Composite: &fnv1.Resource{Resource: composite},
+					Resources: map[string]*fnv1.Resource{
+						"bucket":  {Resource: bucket},
+						"project": {Resource: tc.args.observed},
+					},
+				},
+				Desired: &fnv1.State{
+					Resources: map[string]*fnv1.Resource{
+						"bucket":  {Resource: bucket},
+						"project": {Resource: tc.args.desired},
+					},
+				},
+			}
+
+			buf := &bytes.Buffer{}
+			f := &Function{
+				log:    logging.NewNopLogger(),
+				Client: client,
+				clock:  func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) },
+				audit:  audit.NewJSONLines(buf),
+			}
+			if _, err := f.RunFunction(context.Background(), req); err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+
+			got := []audit.Entry{}
+			dec := json.NewDecoder(buf)
+			for dec.More() {
+				e := audit.Entry{}
+				if err := dec.Decode(&e); err != nil {
+					t.Fatalf("cannot decode audit entry: %v", err)
+				}
+				got = append(got, e)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want audit entries, +got audit entries:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTokenIdentity(t *testing.T) {
+	// fail the first lookup of the token's user
+	lookups := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		if lookups == 1 {
+			http.Error(w, `{"message": "500 Internal Server Error"}`, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 7, "username": "importer-bot"}`))
+	}))
+	defer srv.Close()
+	client, err := gitlab.NewClient("token", gitlab.WithBaseURL(srv.URL+"/api/v4"), gitlab.WithCustomRetryMax(0))
+	if err != nil {
+		t.Fatalf("cannot create gitlab client: %v", err)
+	}
+
+	f := &Function{log: logging.NewNopLogger(), Client: client}
+	got := []string{}
+	for range 3 {
+		got = append(got, f.tokenIdentity(context.Background()))
+	}
+
+	// a failed lookup should be retried, a successful one kept
+	if diff := cmp.Diff([]string{"", "importer-bot (7)", "importer-bot (7)"}, got); diff != "" {
+		t.Errorf("f.tokenIdentity(...): -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(2, lookups); diff != "" {
+		t.Errorf("f.tokenIdentity(...): -want lookups, +got lookups:\n%s", diff)
+	}
+}
@@ -3,14 +3,26 @@ package main
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
 	"github.com/simon-fredrich/function-gitlab-importer/internal"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/audit"
 	"github.com/simon-fredrich/function-gitlab-importer/internal/gitlabclient"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/guard"
 	"github.com/simon-fredrich/function-gitlab-importer/internal/gvkimplementation"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/metrics"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/policy"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/tracing"
 	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/crossplane/crossplane-runtime/v2/apis/common"
 	"github.com/crossplane/function-sdk-go/errors"
 	"github.com/crossplane/function-sdk-go/logging"
 	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
@@ -23,19 +35,41 @@ import (
 type Function struct {
 	fnv1.UnimplementedFunctionRunnerServiceServer
 
-	Input  *v1beta1.Input
 	Client *gitlab.Client
 
-	log logging.Logger
+	log     logging.Logger
+	clock   func() time.Time
+	version string
+
+	// audit records every decision if set.
+	audit    audit.Sink
+	identity struct {
+		sync.Mutex
+		name string
+	}
+}
+
+// run is the state of a single RunFunction request. Crossplane runs the
+// Function concurrently for different composite resources, so nothing
+// specific to a request is stored on the Function itself.
+type run struct {
+	// in is the input of the request.
+	in *v1beta1.Input
+	// guards configured by the input of the request
+	guards guard.Guards
+	// owner is the UID of the composite resource of the request
+	owner string
 }
 
 // RunFunction runs the Function.
-func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest) (*fnv1.RunFunctionResponse, error) {
+func (f *Function) RunFunction(ctx context.Context, req *fnv1.RunFunctionRequest) (*fnv1.RunFunctionResponse, error) {
 	f.log.Debug("Running function", "tag", req.GetMeta().GetTag())
+	defer metrics.RunFunctionDone(time.Now())
+	ctx, span := tracing.Start(ctx, "RunFunction", attribute.String("tag", req.GetMeta().GetTag()))
+	defer span.End()
 
 	rsp := response.To(req, response.DefaultTTL)
 	in := &v1beta1.Input{}
-	f.Input = in
 	if err := request.GetInput(req, in); err != nil {
 		// You can set a custom status condition on the claim. This allows you to
 		// communicate with the user. See the link below for status condition
@@ -56,6 +90,24 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest)
 		return rsp, nil
 	}
 
+	// adopted resources are owned by the composite resource
+	oxr, err := request.GetObservedCompositeResource(req)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot get observed composite resource"))
+		return rsp, nil
+	}
+
+	guards, err := guard.FromInput(in)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid Function input"))
+		return rsp, nil
+	}
+	if in.RequireApproval {
+		// only wait for approval of imports passing every other guard
+		guards = append(guards, guard.NewApproval(oxr.Resource.GetAnnotations()[guard.ApproveImportAnnotation]))
+	}
+	r := &run{in: in, guards: guards, owner: string(oxr.Resource.GetUID())}
+
 	// get all resources from the request
 	resources, err := internal.GetResources(req)
 	if err != nil {
@@ -79,28 +131,110 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest)
 	}
 
 	// process all resources and return those that need update
-	desResourcesWithUpdate := f.processResources(resources)
+	desResourcesWithUpdate, outcomes := f.processResources(ctx, r, resources)
 
 	// Commit all changes once
 	if err := response.SetDesiredComposedResources(rsp, desResourcesWithUpdate); err != nil {
 		f.log.Debug("Failed to set desired composed resources", "err", err)
 		response.Fatal(rsp, fmt.Errorf("cannot set desired composed resources: %w", err))
+		return rsp, nil
 	}
 
-	// You can set a custom status condition on the claim. This allows you to
-	// communicate with the user. See the link below for status condition
-	// guidance.
-	// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
-	response.ConditionTrue(rsp, "FunctionSuccess", "Success").
-		TargetCompositeAndClaim()
+	// record all imported resources on the composite resource
+	if r.in.ImportSummaryFieldPath != "" {
+		if err := f.writeImportSummary(r, req, rsp, outcomes); err != nil {
+			f.log.Debug("Failed to write import summary", "err", err)
+			response.Warning(rsp, errors.Errorf("cannot write import summary: %w", err)).
+				TargetComposite()
+		}
+	}
+
+	// keep proof of every decision apart from the debug log
+	if f.audit != nil {
+		if err := f.recordAudit(ctx, req, desResourcesWithUpdate, outcomes); err != nil {
+			f.log.Info("Failed to record audit entries", "err", err)
+			response.Warning(rsp, errors.Errorf("cannot record audit entries: %w", err)).
+				TargetComposite()
+		}
+	}
+
+	// report the outcome of every processed resource
+	setResults(rsp, r.in, outcomes)
 
 	return rsp, nil
 }
 
-// processRecources processes gitlab related resources.
-func (f *Function) processResources(resources internal.Resources) map[resource.Name]*resource.DesiredComposed {
+// writeImportSummary writes the import summary of all imported composed
+// resources to the desired composite resource.
+func (f *Function) writeImportSummary(r *run, req *fnv1.RunFunctionRequest, rsp *fnv1.RunFunctionResponse, outcomes []outcome) error {
+	oxr, err := request.GetObservedCompositeResource(req)
+	if err != nil {
+		return errors.Errorf("cannot get observed composite resource: %w", err)
+	}
+	observed, err := getImportSummary(oxr, r.in.ImportSummaryFieldPath)
+	if err != nil {
+		return err
+	}
+
+	dxr, err := request.GetDesiredCompositeResource(req)
+	if err != nil {
+		return errors.Errorf("cannot get desired composite resource: %w", err)
+	}
+	resources, err := request.GetObservedComposedResources(req)
+	if err != nil {
+		return errors.Errorf("cannot get observed composed resources: %w", err)
+	}
+
+	summary := importSummary(outcomes, observed, resources, f.now())
+	if err := setImportSummary(dxr, r.in.ImportSummaryFieldPath, summary); err != nil {
+		return err
+	}
+	return response.SetDesiredCompositeResource(rsp, dxr)
+}
+
+// now returns the current time of the Function's clock.
+func (f *Function) now() time.Time {
+	if f.clock == nil {
+		return time.Now()
+	}
+	return f.clock()
+}
+
+// pendingImport is a composed resource whose external resource already exists
+// and has to be looked up by the importer of its GVK.
+type pendingImport struct {
+	name resource.Name
+	des  *resource.DesiredComposed
+	gvk  schema.GroupVersionKind
+	impl gvkimplementation.Implementation
+
+	// check is the outcome of checking whether the resource has to be
+	// imported. It holds the condition message and the inspected values.
+	check outcome
+}
+
+// processRecources processes gitlab related resources. It returns the desired
+// resources that need an update and the outcome of every processed resource.
+//
+// Resources that need to be imported are collected first and planned with
+// their importer before any of them is imported. This allows importers to
+// look up all resources of a request with as few requests as possible.
+//
+// In dry run mode, resources are imported into copies of their desired
+// resources, which are not returned. Resources imported before stay managed.
+func (f *Function) processResources(ctx context.Context, r *run, resources internal.Resources) (map[resource.Name]*resource.DesiredComposed, []outcome) {
+	ctx, span := tracing.Start(ctx, "processResources")
+	defer span.End()
+
 	// define map to hold desired resources that need an update
 	desResourcesWithUpdate := make(map[resource.Name]*resource.DesiredComposed, len(resources.GetDesired()))
+	outcomes := []outcome{}
+
+	// hold one implementation per GVK, so its importer sees all pending imports
+	implementations := map[schema.GroupVersionKind]gvkimplementation.Implementation{}
+	pending := []pendingImport{}
+	// refuse to adopt external resources that are managed already
+	adopted := adoptions{}
 
 	// iterate through observed resources and filter out gitlab related ones
 	for name, obs := range resources.GetObserved() {
@@ -110,6 +244,9 @@ func (f *Function) processResources(resources internal.Resources) map[resource.N
 		// only process relevant resources
 		obsGVK := obs.Resource.GetObjectKind().GroupVersionKind()
 		if !gvkimplementation.IsAllowed(obsGVK) {
+			if r.in.Explain {
+				outcomes = append(outcomes, ignored(name, obsGVK, "kind is not handled by the function"))
+			}
 			continue
 		}
 
@@ -117,20 +254,111 @@ func (f *Function) processResources(resources internal.Resources) map[resource.N
 		des, ok := resources.GetDesired()[name]
 		if !ok {
 			log.Debug("no corresponding desired resource found; skipping")
+			outcomes = append(outcomes, skipped(name, obsGVK, "no corresponding desired resource found").
+				inspect(inspection{key: "desired resources", value: strings.Join(desiredNames(resources), ", ")}))
 			continue
 		}
 
-		if err := f.ensureExternalName(name, obs, des, obsGVK); err != nil {
-			log.Debug("Failed to ensure external-name", "err", err)
+		impl, ok := implementations[obsGVK]
+		if !ok {
+			impl, ok = gvkimplementation.LookupByGKV(obsGVK)
+			if !ok {
+				if r.in.Explain {
+					outcomes = append(outcomes, ignored(name, obsGVK, "kind has no importer"))
+				}
+				continue
+			}
+			if err := impl.Importer.PassInput(r.in); err != nil {
+				log.Debug("Failed to pass input to importer", "err", err)
+				outcomes = append(outcomes, failed(name, obsGVK, err))
+				continue
+			}
+			implementations[obsGVK] = impl
+		}
+
+		o, needsImport := f.ensureExternalName(ctx, r, name, obs, des, obsGVK, impl)
+		if needsImport {
+			metrics.ImportAttempted(obsGVK)
+			pending = append(pending, pendingImport{name: name, des: des, gvk: obsGVK, impl: impl, check: o})
 			continue
 		}
+		if o.status != outcomeFailed {
+			desResourcesWithUpdate[name] = des
+		}
+		if o.result.ExternalName != "" {
+			adopted.adopt(obsGVK, o.result.ExternalName, name)
+		}
+		outcomes = append(outcomes, o)
+	}
 
-		desResourcesWithUpdate[name] = des
+	// plan all pending imports before importing the first one
+	planned := make([]pendingImport, 0, len(pending))
+	for _, p := range pending {
+		if err := p.impl.Importer.Plan(p.des); err != nil {
+			f.log.Debug("Failed to plan import", "name", p.name, "err", err)
+			metrics.ImportFailed(p.gvk)
+			outcomes = append(outcomes, failed(p.name, p.gvk, err).withCheck(p.check))
+			continue
+		}
+		planned = append(planned, p)
 	}
-	return desResourcesWithUpdate
+
+	// import in a stable order, so the same resource wins a conflict on
+	// every reconcile
+	sort.Slice(planned, func(i, j int) bool { return planned[i].name < planned[j].name })
+	for _, p := range planned {
+		if r.in.DryRun {
+			// import into a copy, so the desired resource stays untouched
+			p.des = &resource.DesiredComposed{Resource: p.des.Resource.DeepCopy(), Ready: p.des.Ready}
+		}
+		ctx, span := tracing.Start(ctx, "importExternalName",
+			tracing.AttrResourceName.String(string(p.name)),
+			tracing.GVK(p.gvk),
+		)
+		result, drift, err := f.importExternalName(ctx, r, p, adopted)
+		tracing.End(span, err)
+		if guard.IsPending(err) {
+			f.log.Debug("Import pending approval", "name", p.name, "reason", err)
+			o := pendingApproval(p.name, p.gvk, err.Error())
+			o.result = result
+			outcomes = append(outcomes, o.withCheck(p.check).inspectLookup())
+			continue
+		}
+		if guard.IsBlocked(err) {
+			f.log.Debug("Import blocked", "name", p.name, "reason", err)
+			o := blocked(p.name, p.gvk, err.Error())
+			o.result = result
+			outcomes = append(outcomes, o.withCheck(p.check).inspectLookup())
+			continue
+		}
+		if err != nil {
+			f.log.Debug("Failed to import external-name", "name", p.name, "err", err)
+			metrics.ImportFailed(p.gvk)
+			o := failed(p.name, p.gvk, err)
+			o.result = result
+			outcomes = append(outcomes, o.withCheck(p.check).inspectLookup())
+			continue
+		}
+		metrics.ImportSucceeded(p.gvk)
+		adopted.adopt(p.gvk, result.ExternalName, p.name)
+		if r.in.DryRun {
+			policies, _ := p.des.Resource.GetStringArray("spec.managementPolicies")
+			outcomes = append(outcomes, wouldImport(p.name, p.gvk, result, policies).withDrift(drift).withCheck(p.check).inspectLookup())
+			continue
+		}
+		desResourcesWithUpdate[p.name] = p.des
+		outcomes = append(outcomes, imported(p.name, p.gvk, result).withDrift(drift).withUnmarked(f.unmarked(r, p)).withCheck(p.check).inspectLookup())
+	}
+
+	sortOutcomes(outcomes)
+	return desResourcesWithUpdate, outcomes
 }
 
-func (f *Function) ensureExternalName(name resource.Name, obs resource.ObservedComposed, des *resource.DesiredComposed, obsGKV schema.GroupVersionKind) error {
+// ensureExternalName copies a managed external-name from the observed to the
+// desired composed resource. It returns true if the external-name is missing
+// and the external resource already exists, i.e. the resource has to be
+// imported. Otherwise it returns the outcome of processing the resource.
+func (f *Function) ensureExternalName(ctx context.Context, r *run, name resource.Name, obs resource.ObservedComposed, des *resource.DesiredComposed, obsGKV schema.GroupVersionKind, impl gvkimplementation.Implementation) (outcome, bool) {
 	log := f.log.WithValues("name", name, "GKV", obsGKV)
 	// Test if external-name already present on observed and if resource need management.
 	externalName := internal.GetExternalNameFromObserved(obs)
@@ -139,61 +367,289 @@ func (f *Function) ensureExternalName(name resource.Name, obs resource.ObservedC
 	if err != nil {
 		log.Debug("cannot get annotation", "external-name annotation string", externalNameAnnotationString, "err", err)
 	}
+	inspected := []inspection{
+		{key: "external-name", value: externalName},
+		{key: externalNameAnnotationString, value: obs.Resource.GetAnnotations()[externalNameAnnotationString]},
+	}
 	if externalName != "" && managed {
 		log.Debug("Copy external-name from observed to desired composed resource...")
 		if err := internal.SetExternalNameOnDesired(des, externalName); err != nil {
-			return err
+			log.Debug("Failed to ensure external-name", "err", err)
+			return failed(name, obsGKV, err).inspect(inspected...), false
 		}
-		err := internal.SetManagedValues(des, f.Input)
-		if err != nil {
-			return err
+		m, strategy := f.management(r, name, obsGKV, &obs, des, func() (map[string]any, error) {
+			if err := f.passClient(r, impl); err != nil {
+				return nil, err
+			}
+			return impl.Importer.Get(ctx, externalName)
+		}, func(policies common.ManagementPolicies) error {
+			if err := f.passClient(r, impl); err != nil {
+				return err
+			}
+			p := pendingImport{name: name, des: des, gvk: obsGKV, impl: impl}
+			result := importer.Result{ExternalName: externalName, FullPath: obs.Resource.GetAnnotations()[internal.AnnotationFullPath]}
+			return f.stampOwner(ctx, r, p, result, policies)
+		})
+		if err := internal.SetManagedValues(des, strategy, m); err != nil {
+			log.Debug("Failed to ensure external-name", "err", err)
+			return failed(name, obsGKV, err).inspect(inspected...), false
 		}
-		return nil
+		// keep the provenance recorded at import time, so it does not churn
+		internal.CopyAnnotationsFromObserved(obs, des, internal.ProvenanceAnnotations...)
+		// the drift is only compared on import
+		internal.CopyAnnotationsFromObserved(obs, des, internal.AnnotationDrift)
+		return alreadyManaged(name, obsGKV, externalName).inspect(inspected...), false
 	}
 
-	// If external-name not present try to import it using a fitting importer implementation.
-	impl, ok := gvkimplementation.LookupByGKV(obsGKV)
-	if !ok {
-		return nil
+	// If external-name not present check whether the resource has to be imported.
+	msg, exists := impl.Handler.CheckResourceExists(obs)
+	inspected = append(inspected, inspection{key: "condition", value: msg})
+	if !exists {
+		return skipped(name, obsGKV, "no existing external resource reported").withCondition(msg).inspect(inspected...), false
 	}
+	log.Debug("Resource already exists; importing external-name", "msg", msg)
+	return outcome{condition: msg}.inspect(inspected...), true
+}
 
-	msg, exists := impl.Handler.CheckResourceExists(obs)
-	if exists {
-		log.Debug("Resource already exists; importing external-name", "msg", msg)
-		if f.Client == nil {
-			// supply function with gitlab client
-			client, err := gitlabclient.LoadClient(f.Input)
-			if err != nil {
-				log.Debug("cannot supply function with gitlab client", "err", err)
-				return errors.Errorf("cannot initialize gitlab client: %w", err)
-			}
-			f.Client = client
-		}
-		// supply importer with client
-		err := impl.Importer.PassClient(f.Client)
-		if err != nil {
-			return err
+// importExternalName imports the external-name of a pending import using the
+// importer of its implementation. External resources adopted by another
+// composed resource are not imported. If the import fails, the returned result
+// still describes the lookup, e.g. the candidates it has considered.
+func (f *Function) importExternalName(ctx context.Context, r *run, p pendingImport, adopted adoptions) (importer.Result, driftReport, error) {
+	log := f.log.WithValues("name", p.name, "GKV", p.gvk)
+	if err := f.passClient(r, p.impl); err != nil {
+		return importer.Result{}, driftReport{}, err
+	}
+	result, err := p.impl.Importer.Import(ctx, p.des)
+	if err != nil {
+		return result, driftReport{}, err
+	}
+	if err := adopted.check(p.gvk, result.ExternalName, p.name); err != nil {
+		return result, driftReport{}, err
+	}
+	obj, err := f.getObject(ctx, r, p, result)
+	if err != nil && r.guards.NeedsObject() {
+		return result, driftReport{}, err
+	}
+	if err := f.checkGuards(r, p, result, obj); err != nil {
+		return result, driftReport{}, err
+	}
+	// failing to fetch the details only needed by the drift report must not
+	// block the import
+	drift := f.drift(r, p, obj, err)
+	m, strategy := f.management(r, p.name, p.gvk, nil, p.des, nil, nil)
+	policies := internal.ManagementPolicies(p.des, strategy, m.Policies)
+	if err := f.stampOwner(ctx, r, p, result, policies); err != nil {
+		return result, driftReport{}, err
+	}
+
+	log.Info("Resource successfully imported!", "external-name", result.ExternalName, "fullPath", result.FullPath)
+	if err := internal.SetExternalNameOnDesired(p.des, result.ExternalName); err != nil {
+		return importer.Result{}, driftReport{}, err
+	}
+	if err := internal.SetManagedValues(p.des, strategy, m); err != nil {
+		return importer.Result{}, driftReport{}, err
+	}
+	internal.SetProvenanceOnDesired(p.des, internal.Provenance{
+		FullPath:       result.FullPath,
+		WebURL:         result.WebURL,
+		ImportedAt:     f.now(),
+		ImportedBy:     f.version,
+		LookupStrategy: result.Strategy,
+	})
+	if r.in.DriftReport != nil && r.in.DriftReport.Annotate && drift.err == nil {
+		if err := internal.SetDriftOnDesired(p.des, drift.fields); err != nil {
+			return importer.Result{}, driftReport{}, err
 		}
-		externalName, err := impl.Importer.Import(des)
+	}
+	return result, drift, nil
+}
+
+// passClient supplies the importer of an implementation with the GitLab
+// client, which is loaded on first use.
+func (f *Function) passClient(r *run, impl gvkimplementation.Implementation) error {
+	if f.Client == nil {
+		// supply function with gitlab client
+		client, err := gitlabclient.LoadClient(r.in)
 		if err != nil {
-			return err
+			f.log.Debug("cannot supply function with gitlab client", "err", err)
+			return errors.Errorf("cannot initialize gitlab client: %w", err)
 		}
+		f.Client = client
+	}
+	return impl.Importer.PassClient(f.Client)
+}
 
-		fullPath, err := impl.Importer.GetContext()
+// management returns how a desired composed resource is managed and the
+// strategy its management policies are merged with. Resources imported under
+// promotion stay observe-only until they are promoted, whatever the strategy.
+// Their promotion progress is recorded on the desired resource. obs is nil
+// for resources imported by the current request. get fetches the GitLab
+// resource the desired resource is compared with until it is promoted. stamp
+// writes the owner marker once the resource is promoted; the promotion is
+// postponed until it succeeds.
+func (f *Function) management(r *run, name resource.Name, gvk schema.GroupVersionKind, obs *resource.ObservedComposed, des *resource.DesiredComposed, get func() (map[string]any, error), stamp func(common.ManagementPolicies) error) (policy.Management, v1beta1.ManagementPoliciesStrategy) {
+	m := policy.ManagementOf(r.in, gvk, name)
+	if r.in.Promotion == nil {
+		return m, r.in.ManagementPoliciesStrategy
+	}
+
+	p := internal.Promotion{}
+	if obs != nil {
+		progress, tracked, err := internal.GetPromotionFromObserved(*obs)
 		if err != nil {
-			return err
+			// a malformed progress must not keep the resource from its
+			// external-name, so promotion starts over
+			f.log.Info("Cannot get promotion progress, restarting promotion", "name", name, "err", err)
+			progress = internal.Promotion{}
 		}
-		log.Info("Resource successfully imported!", "external-name", externalName, "fullPath", fullPath)
-		if err := internal.SetExternalNameOnDesired(des, externalName); err != nil {
-			return err
+		if !tracked {
+			// resources imported before promotion was enabled stay managed
+			return m, r.in.ManagementPoliciesStrategy
 		}
-		err = internal.SetManagedValues(des, f.Input)
-		if err != nil {
-			return err
+		p = progress
+		if !progress.Promoted() {
+			obj, err := get()
+			if err != nil {
+				// a failed lookup neither counts as a match nor as a mismatch
+				f.log.Info("Cannot get external resource, keeping promotion progress", "name", name, "err", err)
+			} else {
+				matches, reason := internal.ObservedMatchesDesired(*obs, des, obj)
+				if !matches {
+					f.log.Debug("Observed state does not match desired state", "name", name, "reason", reason)
+				}
+				p = progress.Next(r.in.Promotion, matches, f.now())
+			}
+		}
+		if p.Promoted() && !progress.Promoted() {
+			if err := stamp(internal.ManagementPolicies(des, r.in.ManagementPoliciesStrategy, m.Policies)); err != nil {
+				f.log.Info("Cannot stamp owner marker, postponing promotion", "name", name, "err", err)
+				p.PromotedAt = time.Time{}
+			} else {
+				f.log.Info("Promoted resource", "name", name, "managementPolicies", m.Policies)
+			}
 		}
+	}
+	internal.SetPromotionOnDesired(des, p)
+	if p.Promoted() {
+		return m, r.in.ManagementPoliciesStrategy
+	}
+	m.Policies = common.ManagementPolicies{common.ManagementActionObserve}
+	return m, v1beta1.ManagementPoliciesStrategyOverride
+}
 
+// getObject fetches the external resource found for a pending import, as
+// returned by the GitLab API. It is only fetched if a guard needs it or a
+// drift report is requested, otherwise nil is returned.
+func (f *Function) getObject(ctx context.Context, r *run, p pendingImport, result importer.Result) (map[string]any, error) {
+	if !r.guards.NeedsObject() && r.in.DriftReport == nil {
+		return nil, nil
+	}
+	obj, err := p.impl.Importer.Get(ctx, result.ExternalName)
+	if err != nil {
+		return nil, errors.Errorf("cannot get external resource %s: %w", result.ExternalName, err)
+	}
+	return obj, nil
+}
+
+// checkGuards checks whether the external resource found for a pending import
+// may be imported. obj is only set if a guard needs it.
+func (f *Function) checkGuards(r *run, p pendingImport, result importer.Result, obj map[string]any) error {
+	if len(r.guards) == 0 {
 		return nil
 	}
+	return r.guards.Check(guard.Subject{Name: p.name, GVK: p.gvk, Desired: p.des, Owner: r.owner, Result: result, Object: obj})
+}
+
+// driftReport is the drift of an imported resource from its GitLab resource.
+type driftReport struct {
+	fields []internal.FieldDrift
+	// err is set if the drift is unavailable, as the GitLab resource could
+	// not be fetched.
+	err error
+}
+
+// drift returns the fields of the desired resource of a pending import that
+// differ from the external resource obj, if a drift report is requested. err
+// is the error fetching obj, which makes the drift unavailable.
+func (f *Function) drift(r *run, p pendingImport, obj map[string]any, err error) driftReport {
+	if r.in.DriftReport == nil {
+		return driftReport{}
+	}
+	if err != nil {
+		f.log.Info("Drift unavailable", "name", p.name, "err", err)
+		return driftReport{err: err}
+	}
+	drift := internal.Drift(p.des, obj)
+	if len(drift) > 0 {
+		f.log.Debug("Desired resource drifts from GitLab", "name", p.name, "fields", len(drift))
+	}
+	return driftReport{fields: drift}
+}
 
+// stampOwner writes an owner marker to the external resource if configured,
+// so later reconciles and other clusters can see who owns it. Nothing is
+// written in dry run mode or if the management policies the resource is
+// imported with do not allow updates.
+func (f *Function) stampOwner(ctx context.Context, r *run, p pendingImport, result importer.Result, policies common.ManagementPolicies) error {
+	o := r.in.Ownership
+	if o == nil || o.Stamp == "" || r.in.DryRun {
+		return nil
+	}
+	if !internal.AllowsUpdates(policies) {
+		f.log.Debug("Not stamping owner marker, management policies do not allow updates", "name", p.name)
+		return nil
+	}
+	if r.owner == "" {
+		return errors.New("cannot stamp owner marker: composite resource has no uid")
+	}
+	key := o.Key
+	if key == "" {
+		key = guard.DefaultOwnerKey
+	}
+	changed, err := p.impl.Importer.Stamp(ctx, result.ExternalName, importer.Marker{Type: o.Stamp, Key: key, Value: r.owner})
+	if err != nil {
+		return errors.Errorf("cannot stamp owner marker on %s: %w", result.FullPath, err)
+	}
+	if changed {
+		f.log.Info("Stamped owner marker", "name", p.name, "fullPath", result.FullPath, "type", o.Stamp)
+	}
 	return nil
 }
+
+// desiredNames returns the names of all desired composed resources in
+// alphabetical order.
+// unmarked returns why no owner marker is stamped on the GitLab resource of an
+// imported resource, or an empty string if it is. Without a marker, other
+// composite resources cannot tell that the GitLab resource is adopted already.
+// Resources under promotion are judged by the policies they are promoted to.
+func (f *Function) unmarked(r *run, p pendingImport) string {
+	o := r.in.Ownership
+	switch {
+	case o == nil:
+		return "ownership is not set"
+	case o.Stamp == "":
+		return "ownership.stamp is not set"
+	}
+	policies := policy.ManagementOf(r.in, p.gvk, p.name).Policies
+	if r.in.Promotion == nil {
+		set, _ := p.des.Resource.GetStringArray("spec.managementPolicies")
+		policies = common.ManagementPolicies{}
+		for _, s := range set {
+			policies = append(policies, common.ManagementAction(s))
+		}
+	}
+	if !internal.AllowsUpdates(policies) {
+		return "managementPolicies do not allow updates"
+	}
+	return ""
+}
+
+func desiredNames(resources internal.Resources) []string {
+	names := make([]string, 0, len(resources.GetDesired()))
+	for name := range resources.GetDesired() {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return names
+}
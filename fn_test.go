@@ -2,17 +2,28 @@ package main
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
 	"github.com/simon-fredrich/function-gitlab-importer/internal/testutils"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
 
+	"github.com/crossplane/crossplane-runtime/v2/apis/common"
 	"github.com/crossplane/function-sdk-go/logging"
 	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
 	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
 	"github.com/crossplane/function-sdk-go/response"
 )
 
@@ -24,8 +35,137 @@ func TestRunFunction(t *testing.T) {
 	}
 	externalNameExisting := string(externalNameExistingData)
 
+	filename = "external-name-missing.json"
+	externalNameMissingData, err := testutils.LoadDataFromFile(filename)
+	if err != nil {
+		t.Errorf("cannot load data from file %s: %s", filename, err.Error())
+	}
+	externalNameMissing := string(externalNameMissingData)
+
+	xr := `{"apiVersion": "gitlab.example.org/v1alpha1", "kind": "SimpleProject", "metadata": {"name": "xr"}}`
+	desiredProject := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"spec": {"forProvider": {"namespaceId": 1, "path": "project-to-import"}}
+	}`
+	importedProject := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"metadata": {
+			"annotations": {
+				"crossplane.io/external-name": "42",
+				"crossplane.io/managed-external-name": "true",
+				"gitlab-importer.fn.crossplane.io/full-path": "team/project-to-import",
+				"gitlab-importer.fn.crossplane.io/web-url": "https://gitlab.example.com/team/project-to-import",
+				"gitlab-importer.fn.crossplane.io/imported-at": "2026-10-18T12:00:00Z",
+				"gitlab-importer.fn.crossplane.io/imported-by": "v0.1.0",
+				"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search"
+			}
+		},
+		"spec": {
+			"forProvider": {"namespaceId": 1, "path": "project-to-import"},
+			"managementPolicies": ["Observe"]
+		}
+	}`
+	// a project imported under promotion, which stays observe-only
+	projectUnderPromotion := strings.ReplaceAll(importedProject, `"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search"`,
+		`"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search", "gitlab-importer.fn.crossplane.io/promotion-matches": "0"`)
+	// a project whose visibility drifts from GitLab
+	driftingProject := strings.ReplaceAll(desiredProject, `"path": "project-to-import"`, `"path": "project-to-import", "visibility": "private"`)
+	importedDriftingProject := strings.ReplaceAll(strings.ReplaceAll(importedProject, `"path": "project-to-import"`, `"path": "project-to-import", "visibility": "private"`),
+		`"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search"`,
+		`"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search", "gitlab-importer.fn.crossplane.io/drift": "[{\"field\":\"visibility\",\"desired\":\"private\",\"gitlab\":\"public\"}]"`)
+	// a project imported on a previous reconcile
+	previouslyImportedProject := strings.ReplaceAll(importedProject, "2026-10-18T12:00:00Z", "2026-10-01T08:00:00Z")
+	// a managed project an earlier pipeline step would delete with the composite resource
+	deletedProject := strings.ReplaceAll(desiredProject, `"spec": {`, `"spec": {"deletionPolicy": "Delete", `)
+	orphanedProject := strings.ReplaceAll(previouslyImportedProject, `"managementPolicies": ["Observe"]`, `"managementPolicies": ["Observe"], "deletionPolicy": "Orphan"`)
+	// a project imported under promotion on a previous reconcile, which
+	// matched GitLab once and is synced
+	observedUnderPromotion := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"metadata": {
+			"annotations": {
+				"crossplane.io/external-name": "42",
+				"crossplane.io/managed-external-name": "true",
+				"gitlab-importer.fn.crossplane.io/full-path": "team/project-to-import",
+				"gitlab-importer.fn.crossplane.io/web-url": "https://gitlab.example.com/team/project-to-import",
+				"gitlab-importer.fn.crossplane.io/imported-at": "2026-10-01T08:00:00Z",
+				"gitlab-importer.fn.crossplane.io/imported-by": "v0.1.0",
+				"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search",
+				"gitlab-importer.fn.crossplane.io/promotion-matches": "1",
+				"gitlab-importer.fn.crossplane.io/promotion-matching-since": "2026-10-18T11:00:00Z"
+			}
+		},
+		"spec": {
+			"forProvider": {"namespaceId": 1, "path": "project-to-import"},
+			"managementPolicies": ["Observe"]
+		},
+		"status": {"conditions": [{"type": "Synced", "status": "True", "reason": "ReconcileSuccess"}]}
+	}`
+	// withPromotion returns a previously imported project with the given
+	// promotion annotations and management policies
+	withPromotion := func(project, annotations, policies string) string {
+		project = strings.ReplaceAll(project, `"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search"`,
+			`"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search", `+annotations)
+		return strings.ReplaceAll(project, `"managementPolicies": ["Observe"]`, `"managementPolicies": `+policies)
+	}
+	// a project whose details cannot be fetched
+	desiredUnavailableProject := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"spec": {"forProvider": {"namespaceId": 2, "path": "unavailable-project", "visibility": "private"}}
+	}`
+	importedUnavailableProject := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"metadata": {
+			"annotations": {
+				"crossplane.io/external-name": "43",
+				"crossplane.io/managed-external-name": "true",
+				"gitlab-importer.fn.crossplane.io/full-path": "team/unavailable-project",
+				"gitlab-importer.fn.crossplane.io/web-url": "https://gitlab.example.com/team/unavailable-project",
+				"gitlab-importer.fn.crossplane.io/imported-at": "2026-10-18T12:00:00Z",
+				"gitlab-importer.fn.crossplane.io/imported-by": "v0.1.0",
+				"gitlab-importer.fn.crossplane.io/lookup-strategy": "REST/search"
+			}
+		},
+		"spec": {
+			"forProvider": {"namespaceId": 2, "path": "unavailable-project", "visibility": "private"},
+			"managementPolicies": ["Observe"]
+		}
+	}`
+	desiredMissingProject := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"spec": {"forProvider": {"namespaceId": 1, "path": "missing-project"}}
+	}`
+
+	// serve the projects of namespace 1 like GitLab does
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v4/groups/1/projects":
+			_, _ = w.Write([]byte(`[{"id": 42, "path": "project-to-import", "path_with_namespace": "team/project-to-import", "web_url": "https://gitlab.example.com/team/project-to-import"}]`))
+		case "/api/v4/projects/42":
+			_, _ = w.Write([]byte(`{"id": 42, "path": "project-to-import", "path_with_namespace": "team/project-to-import", "visibility": "public", "archived": false}`))
+		case "/api/v4/groups/2/projects":
+			_, _ = w.Write([]byte(`[{"id": 43, "path": "unavailable-project", "path_with_namespace": "team/unavailable-project", "web_url": "https://gitlab.example.com/team/unavailable-project"}]`))
+		case "/api/v4/projects/43":
+			http.Error(w, `{"message": "500 Internal Server Error"}`, http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	// do not retry server errors, which are served on purpose
+	client, err := gitlab.NewClient("token", gitlab.WithBaseURL(srv.URL+"/api/v4"), gitlab.WithCustomRetryMax(0))
+	if err != nil {
+		t.Fatalf("cannot create gitlab client: %v", err)
+	}
+
 	type args struct {
-		ctx context.Context
 		req *fnv1.RunFunctionRequest
 	}
 	type want struct {
@@ -66,12 +206,935 @@ func TestRunFunction(t *testing.T) {
 				},
 			},
 		},
+		"ReportImportedProject": {
+			reason: "function should import an existing project and report it",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "import"},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(importedProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": imported external resource 42 (team/project-to-import)`,
+							Reason:   ptr.To("Imported"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": external resource 42 (team/project-to-import) carries no owner marker, so other composite resources may adopt it as well: ownership is not set`,
+							Reason:   ptr.To("Unmarked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"KeepProvenanceOfManagedProject": {
+			reason: "function should keep the provenance recorded when the project has been imported",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "import"},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(previouslyImportedProject)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(previouslyImportedProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": skipped: external-name 42 is already managed`,
+							Reason:   ptr.To("Skipped"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"EnforceDeletionPolicy": {
+			reason: "function should keep orphaning a managed project even if an earlier step deletes it",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "deletionPolicy": "Orphan"}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(previouslyImportedProject)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(deletedProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(orphanedProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": skipped: external-name 42 is already managed`,
+							Reason:   ptr.To("Skipped"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"RecordImportSummary": {
+			reason: "function should record imported projects on the status of the composite resource",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "importSummaryFieldPath": "status.importedResources"}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"status": {
+									"importedResources": [{
+										"name": "project",
+										"id": "42",
+										"fullPath": "team/project-to-import",
+										"webUrl": "https://gitlab.example.com/team/project-to-import",
+										"importedAt": "2026-10-18T12:00:00Z"
+									}]
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(importedProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": imported external resource 42 (team/project-to-import)`,
+							Reason:   ptr.To("Imported"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": external resource 42 (team/project-to-import) carries no owner marker, so other composite resources may adopt it as well: ownership is not set`,
+							Reason:   ptr.To("Unmarked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ReportFailedImport": {
+			reason: "function should report a failed import and a false FunctionSuccess condition",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "import"},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredMissingProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredMissingProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": import failed: cannot import resource: there is no project with matching path in namespace with ID 1`,
+							Reason:   ptr.To("Failed"),
+							Target:   fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "FunctionSuccess",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "ImportFailed",
+							Message: ptr.To("1 of 1 GitLab resources could not be imported"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ExplainDecisions": {
+			reason: "function should explain the decision about every observed composed resource in explain mode",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "explain": true}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"bucket":  {Resource: resource.MustStructJSON(`{"apiVersion": "s3.aws.upbound.io/v1beta1", "kind": "Bucket"}`)},
+							"removed": {Resource: resource.MustStructJSON(externalNameMissing)},
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(importedProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "bucket": ignored: kind is not handled by the function; inspected gvk="s3.aws.upbound.io/v1beta1, Kind=Bucket"`,
+							Reason:   ptr.To("Ignored"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message: `composed resource "project": imported external resource 42 (team/project-to-import); inspected gvk="projects.gitlab.crossplane.io/v1alpha1, Kind=Project", ` +
+								`external-name="", crossplane.io/managed-external-name="", ` +
+								`condition="create failed: cannot create Gitlab project: POST https://gitlab.com/api/v4/projects: 400 {message: {name: [has already been taken]}, {path: [has already been taken]}, {project_namespace.name: [has already been taken]}}", ` +
+								`lookup strategy="REST/search", candidates="42 (team/project-to-import)"`,
+							Reason: ptr.To("Imported"),
+							Target: fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": external resource 42 (team/project-to-import) carries no owner marker, so other composite resources may adopt it as well: ownership is not set`,
+							Reason:   ptr.To("Unmarked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "removed": skipped: no corresponding desired resource found; inspected gvk="projects.gitlab.crossplane.io/v1alpha1, Kind=Project", desired resources="project"`,
+							Reason:   ptr.To("Skipped"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"DryRunImport": {
+			reason: "function should only report what it would set on an existing project in dry run mode",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "dryRun": true}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": dry run: would set external-name 42 (team/project-to-import) and managementPolicies [Observe]`,
+							Reason:   ptr.To("WouldImport"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"BlockImportByCondition": {
+			reason: "function should not import a project failing an import condition",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "importConditions": [{"kind": "Project", "expression": "gitlab.visibility == 'private'", "message": "only private projects may be imported"}]}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": import blocked: only private projects may be imported`,
+							Reason:   ptr.To("Blocked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"RefuseDoubleAdoption": {
+			reason: "function should not let two composed resources adopt the same project",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "import"},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project":      {Resource: resource.MustStructJSON(externalNameMissing)},
+							"project-copy": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project":      {Resource: resource.MustStructJSON(desiredProject)},
+							"project-copy": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project":      {Resource: resource.MustStructJSON(importedProject)},
+							"project-copy": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": imported external resource 42 (team/project-to-import)`,
+							Reason:   ptr.To("Imported"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": external resource 42 (team/project-to-import) carries no owner marker, so other composite resources may adopt it as well: ownership is not set`,
+							Reason:   ptr.To("Unmarked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project-copy": import blocked: Project 42 is already adopted by composed resource "project"`,
+							Reason:   ptr.To("Blocked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"WaitForApproval": {
+			reason: "function should only report an existing project until its import is approved",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "requireApproval": true}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": pending approval: found 42 (team/project-to-import), approve its import by annotating the composite resource with gitlab-importer.fn.crossplane.io/approve-import: team/project-to-import`,
+							Reason:   ptr.To("Pending"),
+							Target:   fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ImportUnderPromotion": {
+			reason: "function should import a project observe-only until it is promoted",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "managementPolicies": ["*"], "promotion": {"reconciles": 3}}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(projectUnderPromotion)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": imported external resource 42 (team/project-to-import)`,
+							Reason:   ptr.To("Imported"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": external resource 42 (team/project-to-import) carries no owner marker, so other composite resources may adopt it as well: ownership is not set`,
+							Reason:   ptr.To("Unmarked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ReportDrift": {
+			reason: "function should report the fields of an imported project differing from GitLab",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "driftReport": {"annotate": true}}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(driftingProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(importedDriftingProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": imported external resource 42 (team/project-to-import)`,
+							Reason:   ptr.To("Imported"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": drift from external resource 42 (team/project-to-import): visibility: "public" in GitLab, desired "private"`,
+							Reason:   ptr.To("Drift"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": external resource 42 (team/project-to-import) carries no owner marker, so other composite resources may adopt it as well: ownership is not set`,
+							Reason:   ptr.To("Unmarked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"CountUpPromotion": {
+			reason: "function should count a reconcile at which a project under promotion matches GitLab",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "managementPolicies": ["*"], "promotion": {"reconciles": 3}}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(observedUnderPromotion)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(withPromotion(previouslyImportedProject, `"gitlab-importer.fn.crossplane.io/promotion-matches": "2", "gitlab-importer.fn.crossplane.io/promotion-matching-since": "2026-10-18T11:00:00Z"`, `["Observe"]`))},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": skipped: external-name 42 is already managed`,
+							Reason:   ptr.To("Skipped"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"PromoteMatchingProject": {
+			reason: "function should promote a project to its management policies once it matched GitLab long enough",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "managementPolicies": ["*"], "promotion": {"reconciles": 2}}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(observedUnderPromotion)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(withPromotion(previouslyImportedProject, `"gitlab-importer.fn.crossplane.io/promotion-matches": "2", "gitlab-importer.fn.crossplane.io/promotion-matching-since": "2026-10-18T11:00:00Z", "gitlab-importer.fn.crossplane.io/promoted-at": "2026-10-18T12:00:00Z"`, `["*"]`))},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": skipped: external-name 42 is already managed`,
+							Reason:   ptr.To("Skipped"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ResetPromotionOnDrift": {
+			reason: "function should reset the promotion of a project drifting from GitLab, even if it is synced",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "managementPolicies": ["*"], "promotion": {"reconciles": 2}}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(observedUnderPromotion)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(driftingProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(withPromotion(strings.ReplaceAll(previouslyImportedProject, `"path": "project-to-import"`, `"path": "project-to-import", "visibility": "private"`), `"gitlab-importer.fn.crossplane.io/promotion-matches": "0"`, `["Observe"]`))},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": skipped: external-name 42 is already managed`,
+							Reason:   ptr.To("Skipped"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"RestartMalformedPromotion": {
+			reason: "function should restart the promotion of a project with a malformed progress and keep its external-name and management policies",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "managementPolicies": ["*"], "promotion": {"reconciles": 3}}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(strings.ReplaceAll(observedUnderPromotion, `"gitlab-importer.fn.crossplane.io/promotion-matches": "1"`, `"gitlab-importer.fn.crossplane.io/promotion-matches": "abc"`))},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(withPromotion(previouslyImportedProject, `"gitlab-importer.fn.crossplane.io/promotion-matches": "1", "gitlab-importer.fn.crossplane.io/promotion-matching-since": "2026-10-18T12:00:00Z"`, `["Observe"]`))},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": skipped: external-name 42 is already managed`,
+							Reason:   ptr.To("Skipped"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ImportDespiteUnavailableDrift": {
+			reason: "function should import a project even if its details for the drift report cannot be fetched",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "driftReport": {"annotate": true}}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredUnavailableProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(importedUnavailableProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `composed resource "project": imported external resource 43 (team/unavailable-project)`,
+							Reason:   ptr.To("Imported"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": drift from external resource 43 (team/unavailable-project) unavailable: cannot get external resource 43: cannot get project 43: GET ` + srv.URL + `/api/v4/projects/43: 500 {message: 500 Internal Server Error}`,
+							Reason:   ptr.To("Drift"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `composed resource "project": external resource 43 (team/unavailable-project) carries no owner marker, so other composite resources may adopt it as well: ownership is not set`,
+							Reason:   ptr.To("Unmarked"),
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"FailImportFatally": {
+			reason: "function should stop the pipeline if a failed import has the error policy Fatal",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:  &fnv1.RequestMeta{Tag: "import"},
+					Input: resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "errorPolicyOverrides": [{"name": "project", "policy": "Fatal"}]}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(externalNameMissing)},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredMissingProject)},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "import", Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredMissingProject)},
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  `composed resource "project": import failed: cannot import resource: there is no project with matching path in namespace with ID 1`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "FunctionSuccess",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "ImportFailed",
+							Message: ptr.To("1 of 1 GitLab resources could not be imported"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			f := &Function{log: logging.NewNopLogger()}
-			rsp, err := f.RunFunction(tc.args.ctx, tc.args.req)
+			f := &Function{
+				log:     logging.NewNopLogger(),
+				Client:  client,
+				clock:   func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) },
+				version: "v0.1.0",
+			}
+			rsp, err := f.RunFunction(context.Background(), tc.args.req)
 
 			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
 				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
@@ -83,3 +1146,245 @@ func TestRunFunction(t *testing.T) {
 		})
 	}
 }
+
+func TestRunFunctionConcurrently(t *testing.T) {
+	externalNameMissingData, err := testutils.LoadDataFromFile("external-name-missing.json")
+	if err != nil {
+		t.Fatalf("cannot load data from file: %v", err)
+	}
+	desiredProject := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"spec": {"forProvider": {"namespaceId": 1, "path": "project-to-import"}}
+	}`
+	// only the composite resource with uid a owns the project
+	input := `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "ownership": {
+		"markers": [{"type": "UIDMapping"}],
+		"uidMappings": [{"uid": "a", "patterns": [{"fullPath": "team/**"}]}]
+	}}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v4/groups/1/projects":
+			_, _ = w.Write([]byte(`[{"id": 42, "path": "project-to-import", "path_with_namespace": "team/project-to-import"}]`))
+		case "/api/v4/projects/42":
+			_, _ = w.Write([]byte(`{"id": 42, "path": "project-to-import", "path_with_namespace": "team/project-to-import"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client, err := gitlab.NewClient("token", gitlab.WithBaseURL(srv.URL+"/api/v4"))
+	if err != nil {
+		t.Fatalf("cannot create gitlab client: %v", err)
+	}
+
+	// all requests share one Function, like the requests served by the gRPC server
+	f := &Function{log: logging.NewNopLogger(), Client: client}
+	want := map[string]string{"a": "Imported", "b": "Blocked"}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		for uid, reason := range want {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(input),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructJSON(`{"apiVersion": "gitlab.example.org/v1alpha1", "kind": "SimpleProject", "metadata": {"name": "xr-` + uid + `", "uid": "` + uid + `"}}`)},
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(string(externalNameMissingData))},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"project": {Resource: resource.MustStructJSON(desiredProject)},
+						},
+					},
+				}
+				rsp, err := f.RunFunction(context.Background(), req)
+				if err != nil {
+					t.Errorf("f.RunFunction(...): %v", err)
+					return
+				}
+				if got := rsp.GetResults()[0].GetReason(); got != reason {
+					t.Errorf("f.RunFunction(...) of composite resource %s: want reason %s, got %s: %s", uid, reason, got, rsp.GetResults()[0].GetMessage())
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestStampOnPromotion(t *testing.T) {
+	observed := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"metadata": {
+			"annotations": {
+				"crossplane.io/external-name": "42",
+				"crossplane.io/managed-external-name": "true",
+				"gitlab-importer.fn.crossplane.io/full-path": "team/project-to-import",
+				"gitlab-importer.fn.crossplane.io/promotion-matches": "1",
+				"gitlab-importer.fn.crossplane.io/promotion-matching-since": "2026-10-18T11:00:00Z"
+			}
+		},
+		"spec": {
+			"forProvider": {"namespaceId": 1, "path": "project-to-import"},
+			"managementPolicies": ["Observe"]
+		},
+		"status": {"conditions": [{"type": "Synced", "status": "True", "reason": "ReconcileSuccess"}]}
+	}`
+	desiredProject := `{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind": "Project",
+		"spec": {"forProvider": {"namespaceId": 1, "path": "project-to-import"}}
+	}`
+	input := `{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "managementPolicies": ["*"],
+		"promotion": {"reconciles": 2}, "ownership": {"stamp": "Topic"}}`
+
+	type want struct {
+		topics     string
+		policies   []any
+		promotedAt string
+	}
+
+	cases := map[string]struct {
+		reason    string
+		failStamp bool
+		want      want
+	}{
+		"StampOnPromotion": {
+			reason: "The owner marker should be stamped once a resource is promoted to management policies allowing updates.",
+			want:   want{topics: `["crossplane.io/owner=uid"]`, policies: []any{"*"}, promotedAt: "2026-10-18T12:00:00Z"},
+		},
+		"PostponePromotion": {
+			reason:    "The promotion should be postponed if the owner marker cannot be stamped, keeping the external-name.",
+			failStamp: true,
+			want:      want{topics: "[]", policies: []any{"Observe"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			topics := "[]"
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.URL.Path == "/api/v4/projects/42" && r.Method == http.MethodPut && tc.failStamp:
+					http.Error(w, `{"message": "403 Forbidden"}`, http.StatusForbidden)
+					return
+				case r.URL.Path == "/api/v4/projects/42" && r.Method == http.MethodPut:
+					topics = `["crossplane.io/owner=uid"]`
+				case r.URL.Path != "/api/v4/projects/42":
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write([]byte(`{"id": 42, "path": "project-to-import", "path_with_namespace": "team/project-to-import", "topics": ` + topics + `}`))
+			}))
+			t.Cleanup(srv.Close)
+			client, err := gitlab.NewClient("token", gitlab.WithBaseURL(srv.URL+"/api/v4"), gitlab.WithCustomRetryMax(0))
+			if err != nil {
+				t.Fatalf("cannot create gitlab client: %v", err)
+			}
+
+			f := &Function{
+				log:    logging.NewNopLogger(),
+				Client: client,
+				clock:  func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) },
+			}
+			rsp, err := f.RunFunction(context.Background(), &fnv1.RunFunctionRequest{
+				Input: resource.MustStructJSON(input),
+				Observed: &fnv1.State{
+					Composite: &fnv1.Resource{Resource: resource.MustStructJSON(`{"apiVersion": "gitlab.example.org/v1alpha1", "kind": "SimpleProject", "metadata": {"name": "xr", "uid": "uid"}}`)},
+					Resources: map[string]*fnv1.Resource{
+						"project": {Resource: resource.MustStructJSON(observed)},
+					},
+				},
+				Desired: &fnv1.State{
+					Resources: map[string]*fnv1.Resource{
+						"project": {Resource: resource.MustStructJSON(desiredProject)},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+
+			des := rsp.GetDesired().GetResources()["project"].GetResource().AsMap()
+			annotations, _ := des["metadata"].(map[string]any)["annotations"].(map[string]any)
+			got := want{
+				topics:   topics,
+				policies: des["spec"].(map[string]any)["managementPolicies"].([]any),
+			}
+			if at, ok := annotations["gitlab-importer.fn.crossplane.io/promoted-at"].(string); ok {
+				got.promotedAt = at
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff("42", annotations["crossplane.io/external-name"]); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want external-name, +got external-name:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUnmarked(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "projects.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Project"}
+
+	cases := map[string]struct {
+		reason   string
+		in       v1beta1.Input
+		policies []string
+		want     string
+	}{
+		"NoOwnership": {
+			reason:   "Without ownership no marker should be stamped.",
+			policies: []string{"*"},
+			want:     "ownership is not set",
+		},
+		"NoStamp": {
+			reason:   "Ownership markers are only checked, not stamped, without stamp.",
+			in:       v1beta1.Input{Ownership: &v1beta1.Ownership{Markers: []v1beta1.OwnershipMarker{{Type: v1beta1.OwnershipMarkerUIDMapping}}}},
+			policies: []string{"*"},
+			want:     "ownership.stamp is not set",
+		},
+		"ObserveOnly": {
+			reason:   "The marker should not be stamped on resources Crossplane must not update.",
+			in:       v1beta1.Input{Ownership: &v1beta1.Ownership{Stamp: v1beta1.OwnershipMarkerTopic}},
+			policies: []string{"Observe"},
+			want:     "managementPolicies do not allow updates",
+		},
+		"Stamped": {
+			reason:   "The marker should be stamped on resources Crossplane may update.",
+			in:       v1beta1.Input{Ownership: &v1beta1.Ownership{Stamp: v1beta1.OwnershipMarkerTopic}},
+			policies: []string{"Observe", "Update"},
+		},
+		"StampedOnPromotion": {
+			reason: "The marker of resources under promotion should be stamped once they are promoted.",
+			in: v1beta1.Input{
+				Ownership:          &v1beta1.Ownership{Stamp: v1beta1.OwnershipMarkerTopic},
+				ManagementPolicies: common.ManagementPolicies{common.ManagementActionAll},
+				Promotion:          &v1beta1.Promotion{Reconciles: 3},
+			},
+			policies: []string{"Observe"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			des := &resource.DesiredComposed{Resource: composed.New()}
+			if err := des.Resource.SetValue("spec.managementPolicies", tc.policies); err != nil {
+				t.Fatalf("cannot set spec.managementPolicies: %v", err)
+			}
+			f := &Function{log: logging.NewNopLogger()}
+			got := f.unmarked(&run{in: &tc.in}, pendingImport{name: "project", des: des, gvk: gvk})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nf.unmarked(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
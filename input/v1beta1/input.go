@@ -19,4 +19,340 @@ type Input struct {
 
 	BaseURL            string                    `json:"baseURL"`
 	ManagementPolicies common.ManagementPolicies `json:"managementPolicies"`
+
+	// ManagementPoliciesStrategy defines how ManagementPolicies are merged
+	// with management policies set by earlier pipeline steps. Override
+	// replaces them, KeepExisting keeps them and Intersect keeps only those
+	// in ManagementPolicies as well. Defaults to Override.
+	// +kubebuilder:validation:Enum=Override;KeepExisting;Intersect
+	// +optional
+	ManagementPoliciesStrategy ManagementPoliciesStrategy `json:"managementPoliciesStrategy,omitempty"`
+
+	// ManagementRules override ManagementPolicies and set a deletion policy
+	// for selected imported resources. If several rules select a resource,
+	// the most specific one wins: a selected name beats a name pattern,
+	// which beats a selected kind.
+	// +optional
+	ManagementRules []ManagementRule `json:"managementRules,omitempty"`
+
+	// DeletionPolicy of imported resources, e.g. Orphan to keep GitLab
+	// resources that existed before Crossplane when the composite resource
+	// is deleted. ManagementRules may set another deletion policy for
+	// selected resources. The deletion policy is left untouched by default.
+	// +kubebuilder:validation:Enum=Orphan;Delete
+	// +optional
+	DeletionPolicy common.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Promotion adopts resources observe-only and promotes them to their
+	// management policies once their observed state has matched the desired
+	// state long enough. Resources are promoted immediately by default.
+	// +optional
+	Promotion *Promotion `json:"promotion,omitempty"`
+
+	// DriftReport compares the desired spec.forProvider of imported
+	// resources with the GitLab resource found and reports differing fields.
+	// +optional
+	DriftReport *DriftReport `json:"driftReport,omitempty"`
+
+	// LookupBackend selects how existing GitLab resources are looked up.
+	// REST lists the namespaces of all pending imports, GraphQL resolves
+	// their full paths in batched queries. Defaults to REST.
+	// +kubebuilder:validation:Enum=REST;GraphQL
+	// +optional
+	LookupBackend LookupBackend `json:"lookupBackend,omitempty"`
+
+	// MaxListPages caps the number of pages of 100 items the REST backend
+	// lists per namespace. Pending imports beyond the limit fail. Defaults
+	// to 100, i.e. 10,000 groups or projects per namespace.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxListPages int `json:"maxListPages,omitempty"`
+
+	// ErrorPolicy defines how failed imports are handled. Defaults to Warn.
+	// +kubebuilder:validation:Enum=Continue;Warn;Fatal
+	// +optional
+	ErrorPolicy ErrorPolicy `json:"errorPolicy,omitempty"`
+
+	// ErrorPolicyOverrides override the ErrorPolicy for selected composed
+	// resources. If several overrides select a resource, the most specific
+	// one wins: a selected name beats a name pattern, which beats a selected
+	// kind.
+	// +optional
+	ErrorPolicyOverrides []ErrorPolicyOverride `json:"errorPolicyOverrides,omitempty"`
+
+	// ImportSummaryFieldPath is the field path within the status of the
+	// composite resource at which a summary of all imported resources is
+	// written, e.g. status.importedResources. The composite resource's schema
+	// has to define this field. No summary is written if it is empty.
+	// +optional
+	ImportSummaryFieldPath string `json:"importSummaryFieldPath,omitempty"`
+
+	// Explain reports a result for every observed composed resource, naming
+	// the exact reason for what the Function did and the values it inspected
+	// to decide so. This includes resources the Function does not handle.
+	Explain bool `json:"explain,omitempty"`
+
+	// DryRun detects and looks up existing resources as usual, but leaves
+	// the external-name and management policies of desired resources
+	// untouched. Instead, a result describes what would have been set.
+	// Resources imported before stay managed.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// ImportConditions are CEL expressions that all have to be true before
+	// an existing GitLab resource is imported. A false condition blocks the
+	// import of the resource.
+	// +optional
+	ImportConditions []ImportCondition `json:"importConditions,omitempty"`
+
+	// ImportAllow lists the GitLab resources that may be imported. If set,
+	// a GitLab resource has to match at least one pattern to be imported.
+	// +optional
+	ImportAllow []ImportPattern `json:"importAllow,omitempty"`
+
+	// ImportDeny lists the GitLab resources that are never imported. It
+	// takes precedence over ImportAllow.
+	// +optional
+	ImportDeny []ImportPattern `json:"importDeny,omitempty"`
+
+	// RequireApproval only imports GitLab resources whose full paths are
+	// listed by the gitlab-importer.fn.crossplane.io/approve-import
+	// annotation of the composite resource, separated by commas. Until then,
+	// the GitLab resources found are reported as pending.
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+
+	// Ownership requires GitLab resources to carry markers proving that they
+	// belong to the composite resource before they are adopted.
+	// +optional
+	Ownership *Ownership `json:"ownership,omitempty"`
 }
+
+// ResourceSelector selects composed resources. Empty fields match any value.
+type ResourceSelector struct {
+	// APIVersion of the selected resources, e.g. projects.gitlab.crossplane.io/v1alpha1.
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind of the selected resources, e.g. Project.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the composition resource name of the selected resource.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// NamePattern is a glob matched against the composition resource name
+	// of the selected resources, e.g. project-*.
+	// +optional
+	NamePattern string `json:"namePattern,omitempty"`
+}
+
+// ManagementPoliciesStrategy defines how management policies are merged with
+// those set by earlier pipeline steps.
+type ManagementPoliciesStrategy string
+
+const (
+	// ManagementPoliciesStrategyOverride replaces existing management
+	// policies.
+	ManagementPoliciesStrategyOverride ManagementPoliciesStrategy = "Override"
+
+	// ManagementPoliciesStrategyKeepExisting keeps existing management
+	// policies.
+	ManagementPoliciesStrategyKeepExisting ManagementPoliciesStrategy = "KeepExisting"
+
+	// ManagementPoliciesStrategyIntersect keeps the existing management
+	// policies that are allowed by the input as well.
+	ManagementPoliciesStrategyIntersect ManagementPoliciesStrategy = "Intersect"
+)
+
+// ManagementRule defines how selected imported resources are managed.
+type ManagementRule struct {
+	// ResourceSelector selects the composed resources the rule applies to.
+	// It applies to all composed resources by default.
+	ResourceSelector `json:",inline"`
+
+	// ManagementPolicies of the selected resources. Defaults to the
+	// ManagementPolicies of the input.
+	// +optional
+	ManagementPolicies common.ManagementPolicies `json:"managementPolicies,omitempty"`
+
+	// DeletionPolicy of the selected resources. Defaults to the
+	// DeletionPolicy of the input.
+	// +kubebuilder:validation:Enum=Orphan;Delete
+	// +optional
+	DeletionPolicy common.DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// Promotion configures when imported resources are promoted from observe-only
+// to their management policies. Every threshold set has to be reached.
+type Promotion struct {
+	// Reconciles is the number of consecutive reconciles at which the
+	// observed state has to match the desired state.
+	// +optional
+	Reconciles int `json:"reconciles,omitempty"`
+
+	// Duration for which the observed state has to match the desired state,
+	// e.g. 24h.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// DriftReport configures how the drift between imported resources and their
+// GitLab resources is reported.
+type DriftReport struct {
+	// Annotate records the drift in the
+	// gitlab-importer.fn.crossplane.io/drift annotation of the imported
+	// resource, in addition to the result reported.
+	// +optional
+	Annotate bool `json:"annotate,omitempty"`
+}
+
+// ErrorPolicy defines how failed imports are handled.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyContinue reports failed imports as normal results and keeps
+	// the FunctionSuccess condition true.
+	ErrorPolicyContinue ErrorPolicy = "Continue"
+
+	// ErrorPolicyWarn reports failed imports as warnings and sets the
+	// FunctionSuccess condition to false.
+	ErrorPolicyWarn ErrorPolicy = "Warn"
+
+	// ErrorPolicyFatal reports failed imports as fatal results, which stops
+	// the pipeline before the resource is created in vain.
+	ErrorPolicyFatal ErrorPolicy = "Fatal"
+)
+
+// ErrorPolicyOverride overrides the ErrorPolicy for selected composed resources.
+type ErrorPolicyOverride struct {
+	ResourceSelector `json:",inline"`
+
+	// Policy applied to failed imports of the selected resources.
+	// +kubebuilder:validation:Enum=Continue;Warn;Fatal
+	Policy ErrorPolicy `json:"policy"`
+}
+
+// ImportCondition is a CEL expression evaluated before an existing GitLab
+// resource is imported.
+type ImportCondition struct {
+	// ResourceSelector selects the composed resources the condition applies
+	// to. It applies to all composed resources by default.
+	ResourceSelector `json:",inline"`
+
+	// Expression returning whether the GitLab resource may be imported. It
+	// can access the GitLab resource as returned by the GitLab REST API as
+	// gitlab and the desired composed resource as desired, e.g.
+	// gitlab.visibility == "private" && !gitlab.archived.
+	Expression string `json:"expression"`
+
+	// Message reported if the expression is false. Defaults to the
+	// expression itself.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ImportPattern matches GitLab resources by their location. A GitLab resource
+// matches if it matches all fields that are set.
+type ImportPattern struct {
+	// FullPath is a glob matched against the full path of the GitLab
+	// resource. * matches within a single path segment and ** matches any
+	// number of segments, e.g. team-a/** or security/*. The match is
+	// case-insensitive.
+	// +optional
+	FullPath string `json:"fullPath,omitempty"`
+
+	// FullPathRegex is a regular expression matched against the whole full
+	// path of the GitLab resource, e.g. team-(a|b)/.*. The match is
+	// case-insensitive.
+	// +optional
+	FullPathRegex string `json:"fullPathRegex,omitempty"`
+
+	// NamespaceIDs match GitLab resources located directly within one of
+	// the groups with these IDs.
+	// +optional
+	NamespaceIDs []int `json:"namespaceIds,omitempty"`
+}
+
+// Ownership configures the markers proving that a GitLab resource belongs to
+// the composite resource adopting it.
+type Ownership struct {
+	// Key of the owner marker. Topics and description markers are expected
+	// as <key>=<composite resource UID>, custom attributes with the key and
+	// the composite resource UID as value. Defaults to crossplane.io/owner.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Markers that all have to be present on a GitLab resource before it is
+	// adopted.
+	// +optional
+	Markers []OwnershipMarker `json:"markers,omitempty"`
+
+	// UIDMappings map composite resources to the GitLab resources they own.
+	// They are checked by markers of type UIDMapping.
+	// +optional
+	UIDMappings []UIDMapping `json:"uidMappings,omitempty"`
+
+	// Stamp writes an owner marker of the given type to GitLab resources
+	// after they have been imported, so other clusters can see who owns
+	// them. It is only written if the management policies allow updates.
+	// Groups only support CustomAttribute. No marker is written by default.
+	// +kubebuilder:validation:Enum=Topic;CustomAttribute
+	// +optional
+	Stamp OwnershipMarkerType `json:"stamp,omitempty"`
+}
+
+// OwnershipMarkerType is the kind of marker proving ownership.
+type OwnershipMarkerType string
+
+const (
+	// OwnershipMarkerTopic expects a project topic <key>=<uid>. Groups do
+	// not have topics.
+	OwnershipMarkerTopic OwnershipMarkerType = "Topic"
+
+	// OwnershipMarkerCustomAttribute expects the custom attribute <key> with
+	// the value <uid>. GitLab only returns custom attributes to
+	// administrators.
+	OwnershipMarkerCustomAttribute OwnershipMarkerType = "CustomAttribute"
+
+	// OwnershipMarkerDescription expects <key>=<uid> within the description.
+	OwnershipMarkerDescription OwnershipMarkerType = "Description"
+
+	// OwnershipMarkerUIDMapping expects a UID mapping of the composite
+	// resource matching the GitLab resource.
+	OwnershipMarkerUIDMapping OwnershipMarkerType = "UIDMapping"
+)
+
+// OwnershipMarker is a marker required on selected GitLab resources.
+type OwnershipMarker struct {
+	// ResourceSelector selects the composed resources the marker is
+	// required for. It is required for all composed resources by default.
+	ResourceSelector `json:",inline"`
+
+	// Type of the marker.
+	// +kubebuilder:validation:Enum=Topic;CustomAttribute;Description;UIDMapping
+	Type OwnershipMarkerType `json:"type"`
+}
+
+// UIDMapping maps a composite resource to the GitLab resources it owns.
+type UIDMapping struct {
+	// UID of the composite resource.
+	UID string `json:"uid"`
+
+	// Patterns matching the GitLab resources owned by the composite
+	// resource.
+	Patterns []ImportPattern `json:"patterns"`
+}
+
+// LookupBackend is the API used to look up existing GitLab resources.
+type LookupBackend string
+
+const (
+	// LookupBackendREST lists all groups or projects of a namespace using the
+	// REST API and matches their paths locally.
+	LookupBackendREST LookupBackend = "REST"
+
+	// LookupBackendGraphQL resolves the full paths of all pending imports
+	// using batched queries against the GraphQL API.
+	LookupBackendGraphQL LookupBackend = "GraphQL"
+)
@@ -5,14 +5,134 @@
 package v1beta1
 
 import (
+	"github.com/crossplane/crossplane-runtime/v2/apis/common"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftReport) DeepCopyInto(out *DriftReport) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftReport.
+func (in *DriftReport) DeepCopy() *DriftReport {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ErrorPolicyOverride) DeepCopyInto(out *ErrorPolicyOverride) {
+	*out = *in
+	out.ResourceSelector = in.ResourceSelector
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErrorPolicyOverride.
+func (in *ErrorPolicyOverride) DeepCopy() *ErrorPolicyOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ErrorPolicyOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImportCondition) DeepCopyInto(out *ImportCondition) {
+	*out = *in
+	out.ResourceSelector = in.ResourceSelector
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImportCondition.
+func (in *ImportCondition) DeepCopy() *ImportCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ImportCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImportPattern) DeepCopyInto(out *ImportPattern) {
+	*out = *in
+	if in.NamespaceIDs != nil {
+		in, out := &in.NamespaceIDs, &out.NamespaceIDs
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImportPattern.
+func (in *ImportPattern) DeepCopy() *ImportPattern {
+	if in == nil {
+		return nil
+	}
+	out := new(ImportPattern)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Input) DeepCopyInto(out *Input) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.ManagementPolicies != nil {
+		in, out := &in.ManagementPolicies, &out.ManagementPolicies
+		*out = make(common.ManagementPolicies, len(*in))
+		copy(*out, *in)
+	}
+	if in.ManagementRules != nil {
+		in, out := &in.ManagementRules, &out.ManagementRules
+		*out = make([]ManagementRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Promotion != nil {
+		in, out := &in.Promotion, &out.Promotion
+		*out = new(Promotion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriftReport != nil {
+		in, out := &in.DriftReport, &out.DriftReport
+		*out = new(DriftReport)
+		**out = **in
+	}
+	if in.ErrorPolicyOverrides != nil {
+		in, out := &in.ErrorPolicyOverrides, &out.ErrorPolicyOverrides
+		*out = make([]ErrorPolicyOverride, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImportConditions != nil {
+		in, out := &in.ImportConditions, &out.ImportConditions
+		*out = make([]ImportCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImportAllow != nil {
+		in, out := &in.ImportAllow, &out.ImportAllow
+		*out = make([]ImportPattern, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImportDeny != nil {
+		in, out := &in.ImportDeny, &out.ImportDeny
+		*out = make([]ImportPattern, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Ownership != nil {
+		in, out := &in.Ownership, &out.Ownership
+		*out = new(Ownership)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Input.
@@ -32,3 +152,124 @@ func (in *Input) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagementRule) DeepCopyInto(out *ManagementRule) {
+	*out = *in
+	out.ResourceSelector = in.ResourceSelector
+	if in.ManagementPolicies != nil {
+		in, out := &in.ManagementPolicies, &out.ManagementPolicies
+		*out = make(common.ManagementPolicies, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagementRule.
+func (in *ManagementRule) DeepCopy() *ManagementRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagementRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Ownership) DeepCopyInto(out *Ownership) {
+	*out = *in
+	if in.Markers != nil {
+		in, out := &in.Markers, &out.Markers
+		*out = make([]OwnershipMarker, len(*in))
+		copy(*out, *in)
+	}
+	if in.UIDMappings != nil {
+		in, out := &in.UIDMappings, &out.UIDMappings
+		*out = make([]UIDMapping, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Ownership.
+func (in *Ownership) DeepCopy() *Ownership {
+	if in == nil {
+		return nil
+	}
+	out := new(Ownership)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OwnershipMarker) DeepCopyInto(out *OwnershipMarker) {
+	*out = *in
+	out.ResourceSelector = in.ResourceSelector
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OwnershipMarker.
+func (in *OwnershipMarker) DeepCopy() *OwnershipMarker {
+	if in == nil {
+		return nil
+	}
+	out := new(OwnershipMarker)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Promotion) DeepCopyInto(out *Promotion) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Promotion.
+func (in *Promotion) DeepCopy() *Promotion {
+	if in == nil {
+		return nil
+	}
+	out := new(Promotion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceSelector) DeepCopyInto(out *ResourceSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceSelector.
+func (in *ResourceSelector) DeepCopy() *ResourceSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UIDMapping) DeepCopyInto(out *UIDMapping) {
+	*out = *in
+	if in.Patterns != nil {
+		in, out := &in.Patterns, &out.Patterns
+		*out = make([]ImportPattern, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UIDMapping.
+func (in *UIDMapping) DeepCopy() *UIDMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(UIDMapping)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// Stdout is the path that makes Open write to stdout instead of a file.
+const Stdout = "-"
+
+// Entry records a single decision about a composed resource.
+type Entry struct {
+	// Time the decision has been made at.
+	Time time.Time `json:"time"`
+	// Composite is the composite resource the composed resource belongs to.
+	Composite Reference `json:"composite"`
+	// Resource is the composition resource name of the composed resource.
+	Resource string `json:"resource"`
+	// GVK of the composed resource.
+	GVK string `json:"gvk"`
+	// Decision is either Imported, Skipped, Failed, Ignored, WouldImport,
+	// Blocked or Pending. Ignored is only recorded in explain mode and
+	// WouldImport only in dry run mode.
+	Decision string `json:"decision"`
+	// Reason explains why the resource has been skipped, failed, ignored,
+	// blocked or is pending.
+	Reason string `json:"reason,omitempty"`
+	// Condition is the message of the condition reporting that the external
+	// resource already exists.
+	Condition string `json:"condition,omitempty"`
+	// Candidates are the GitLab resources considered by the lookup.
+	Candidates []Candidate `json:"candidates,omitempty"`
+	// ChosenID is the ID of the GitLab resource set as external-name, or
+	// that would have been set in dry run mode.
+	ChosenID string `json:"chosenId,omitempty"`
+	// ManagementPolicies applied to the composed resource. They are only
+	// recorded for resources whose external-name the Function manages.
+	ManagementPolicies []string `json:"managementPolicies,omitempty"`
+	// TokenIdentity is the GitLab user the lookups have been made as.
+	TokenIdentity string `json:"tokenIdentity,omitempty"`
+}
+
+// Reference identifies a Kubernetes object.
+type Reference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	UID        string `json:"uid,omitempty"`
+}
+
+// Candidate is a GitLab resource considered by a lookup.
+type Candidate struct {
+	ID       string `json:"id"`
+	FullPath string `json:"fullPath"`
+}
+
+// A Sink records audit entries.
+type Sink interface {
+	Record(e Entry) error
+}
+
+// JSONLines is a Sink writing every entry as a single line of JSON. It is
+// safe for concurrent use.
+type JSONLines struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLines returns a Sink writing to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w, enc: json.NewEncoder(w)}
+}
+
+// Open returns a Sink appending to the file at path, which is created if it
+// does not exist. It writes to stdout if path is Stdout.
+func Open(path string) (*JSONLines, error) {
+	if path == Stdout {
+		return NewJSONLines(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, errors.Errorf("cannot open audit log: %w", err)
+	}
+	return NewJSONLines(f), nil
+}
+
+// Record writes e as a single line.
+func (s *JSONLines) Record(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(e); err != nil {
+		return errors.Errorf("cannot write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file. Stdout is left open.
+func (s *JSONLines) Close() error {
+	if c, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+// Package audit records every decision the Function makes about a composed
+// resource, such as importing an existing GitLab project, as JSON Lines.
+//
+// The audit log is separate from the debug log. It is meant to be kept as
+// proof of why an existing GitLab resource came under Crossplane control.
+package audit
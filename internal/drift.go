@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// AnnotationDrift records the fields of an imported composed resource whose
+// desired value differs from the GitLab resource found.
+const AnnotationDrift = "gitlab-importer.fn.crossplane.io/drift"
+
+// FieldDrift is a field of spec.forProvider whose desired value differs from
+// the value of the GitLab resource.
+type FieldDrift struct {
+	// Field is the name of the field within spec.forProvider.
+	Field string `json:"field"`
+	// Desired is the value Crossplane would set.
+	Desired any `json:"desired"`
+	// GitLab is the current value of the GitLab resource.
+	GitLab any `json:"gitlab"`
+}
+
+// String returns the drift of the field, e.g. visibility: "private" in GitLab, desired "public".
+func (d FieldDrift) String() string {
+	return fmt.Sprintf("%s: %s in GitLab, desired %s", d.Field, formatValue(d.GitLab), formatValue(d.Desired))
+}
+
+// Drift compares the desired spec.forProvider of a composed resource with the
+// GitLab resource obj, as returned by the GitLab API. Fields are matched by
+// their snake_case name, e.g. defaultBranch with default_branch. Fields GitLab
+// does not return and objects, whose fields are named differently, are not
+// compared. The drift is sorted by field.
+func Drift(des *resource.DesiredComposed, obj map[string]any) []FieldDrift {
+	drift, _ := compare(des, obj)
+	return drift
+}
+
+// compare returns the drift of a composed resource from the GitLab resource
+// obj like Drift and the number of fields compared.
+func compare(des *resource.DesiredComposed, obj map[string]any) ([]FieldDrift, int) {
+	desired := map[string]any{}
+	_ = des.Resource.GetValueInto("spec.forProvider", &desired)
+
+	drift := []FieldDrift{}
+	compared := 0
+	for field, value := range desired {
+		if _, ok := value.(map[string]any); ok {
+			continue
+		}
+		actual, ok := obj[snakeCase(field)]
+		if !ok {
+			continue
+		}
+		compared++
+		if !reflect.DeepEqual(value, actual) {
+			drift = append(drift, FieldDrift{Field: field, Desired: value, GitLab: actual})
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Field < drift[j].Field })
+	return drift, compared
+}
+
+// SetDriftOnDesired records the drift as JSON within the drift annotation of
+// a desired composed resource.
+func SetDriftOnDesired(des *resource.DesiredComposed, drift []FieldDrift) error {
+	b, err := json.Marshal(drift)
+	if err != nil {
+		return errors.Errorf("cannot marshal drift: %w", err)
+	}
+	AddAnnotationOnDesired(des, AnnotationDrift, string(b))
+	return nil
+}
+
+// snakeCase converts a camelCase field name into snake_case.
+func snakeCase(s string) string {
+	b := strings.Builder{}
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// formatValue formats a value of a generic object, quoting strings.
+func formatValue(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestDrift(t *testing.T) {
+	type args struct {
+		forProvider map[string]any
+		obj         map[string]any
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []FieldDrift
+	}{
+		"NoDrift": {
+			reason: "Equal fields should not be reported.",
+			args: args{
+				forProvider: map[string]any{"visibility": "private", "defaultBranch": "main"},
+				obj:         map[string]any{"visibility": "private", "default_branch": "main"},
+			},
+			want: []FieldDrift{},
+		},
+		"Drift": {
+			reason: "Differing fields should be reported by their desired name, sorted by field.",
+			args: args{
+				forProvider: map[string]any{"visibility": "public", "defaultBranch": "main", "description": "backend"},
+				obj:         map[string]any{"visibility": "private", "default_branch": "master", "description": "backend"},
+			},
+			want: []FieldDrift{
+				{Field: "defaultBranch", Desired: "main", GitLab: "master"},
+				{Field: "visibility", Desired: "public", GitLab: "private"},
+			},
+		},
+		"SkipUnknownFields": {
+			reason: "Fields GitLab does not return should not be compared.",
+			args: args{
+				forProvider: map[string]any{"namespaceId": float64(1), "namespaceIdRef": map[string]any{"name": "team"}},
+				obj:         map[string]any{"namespace": map[string]any{"id": float64(2)}},
+			},
+			want: []FieldDrift{},
+		},
+		"SkipObjects": {
+			reason: "Objects should not be compared, as their fields are named differently.",
+			args: args{
+				forProvider: map[string]any{"containerExpirationPolicy": map[string]any{"enabled": true}},
+				obj:         map[string]any{"container_expiration_policy": map[string]any{"enabled": false}},
+			},
+			want: []FieldDrift{},
+		},
+		"Lists": {
+			reason: "Lists should be compared as a whole.",
+			args: args{
+				forProvider: map[string]any{"topics": []any{"backend", "go"}},
+				obj:         map[string]any{"topics": []any{"backend"}},
+			},
+			want: []FieldDrift{{Field: "topics", Desired: []any{"backend", "go"}, GitLab: []any{"backend"}}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			des := &resource.DesiredComposed{Resource: composed.New()}
+			if err := des.Resource.SetValue("spec.forProvider", tc.args.forProvider); err != nil {
+				t.Fatalf("cannot set spec.forProvider: %v", err)
+			}
+			got := Drift(des, tc.args.obj)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nDrift(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package gitlabclient
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/metrics"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/tracing"
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 
 	"github.com/crossplane/function-sdk-go/errors"
@@ -40,7 +45,7 @@ func LoadClient(in *v1beta1.Input) (*gitlab.Client, error) {
 	// either use BaseURL from input or from environment
 	if BaseURL != "" {
 		// create a new instance of the gitlab api "client-go" using BaseURL from input
-		client, err := gitlab.NewClient(token, gitlab.WithBaseURL(BaseURL+"/api/v4"))
+		client, err := gitlab.NewClient(token, gitlab.WithBaseURL(BaseURL+"/api/v4"), withInstrumentation())
 		if err != nil {
 			return nil, errors.Errorf("creating new client for gitlab api using input: %w", err)
 		}
@@ -57,7 +62,7 @@ func LoadClient(in *v1beta1.Input) (*gitlab.Client, error) {
 	}
 
 	// create a new instance of the gitlab api "client-go" using BaseURL from environment or default
-	client, err := gitlab.NewClient(token, gitlab.WithBaseURL(BaseURL+"/api/v4"))
+	client, err := gitlab.NewClient(token, gitlab.WithBaseURL(BaseURL+"/api/v4"), withInstrumentation())
 	if err != nil {
 		return nil, errors.Errorf("creating new client for gitlab api using env: %w", err)
 	}
@@ -66,3 +71,22 @@ func LoadClient(in *v1beta1.Input) (*gitlab.Client, error) {
 	}
 	return client, nil
 }
+
+// withInstrumentation records the latency and status code of every request
+// sent by the client and traces it within its own span.
+func withInstrumentation() gitlab.ClientOptionFunc {
+	transport := metrics.InstrumentTransport(http.DefaultTransport.(*http.Transport).Clone())
+	return gitlab.WithHTTPClient(&http.Client{
+		Transport: tracing.InstrumentTransport(transport),
+	})
+}
+
+// TokenIdentity returns the user the token of the client belongs to, formatted
+// as "<username> (<id>)".
+func TokenIdentity(ctx context.Context, client *gitlab.Client) (string, error) {
+	user, _, err := client.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		return "", errors.Errorf("cannot get current user: %w", err)
+	}
+	return fmt.Sprintf("%s (%d)", user.Username, user.ID), nil
+}
@@ -0,0 +1,64 @@
+package guard
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// ApproveImportAnnotation lists the full paths of the external resources a
+// composite resource may import, separated by commas.
+const ApproveImportAnnotation = "gitlab-importer.fn.crossplane.io/approve-import"
+
+// PendingError is returned by guards that wait for an import to be approved.
+type PendingError struct {
+	// Reason the import is pending for.
+	Reason string
+}
+
+// Error returns the reason the import is pending for.
+func (e *PendingError) Error() string {
+	return e.Reason
+}
+
+// IsPending returns true if err is or wraps a PendingError.
+func IsPending(err error) bool {
+	var p *PendingError
+	return errors.As(err, &p)
+}
+
+// Approval is a Guard only allowing imports approved by the approve import
+// annotation of the composite resource.
+type Approval struct {
+	approved []string
+}
+
+// NewApproval returns an Approval allowing the full paths listed by the
+// value of the approve import annotation.
+func NewApproval(annotation string) *Approval {
+	a := &Approval{}
+	for _, fullPath := range strings.Split(annotation, ",") {
+		if fullPath = strings.TrimSpace(fullPath); fullPath != "" {
+			a.approved = append(a.approved, fullPath)
+		}
+	}
+	return a
+}
+
+// Check returns a PendingError naming the external resource and how to
+// approve its import, unless its full path has been approved. GitLab paths
+// are case-insensitive, so are the approved ones.
+func (a *Approval) Check(s Subject) error {
+	if slices.ContainsFunc(a.approved, func(fullPath string) bool { return strings.EqualFold(fullPath, s.Result.FullPath) }) {
+		return nil
+	}
+	return &PendingError{Reason: fmt.Sprintf("found %s (%s), approve its import by annotating the composite resource with %s: %s",
+		s.Result.ExternalName, s.Result.FullPath, ApproveImportAnnotation, s.Result.FullPath)}
+}
+
+// NeedsObject returns false, as the full path is part of the lookup result.
+func (a *Approval) NeedsObject() bool {
+	return false
+}
@@ -0,0 +1,49 @@
+package guard
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+)
+
+func TestApproval(t *testing.T) {
+	type args struct {
+		annotation string
+		fullPath   string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   bool
+	}{
+		"NotAnnotated": {
+			reason: "Imports should be pending without an approval.",
+			args:   args{fullPath: "team/project"},
+			want:   true,
+		},
+		"Approved": {
+			reason: "Imports of a listed full path should be allowed.",
+			args:   args{annotation: "team/other, team/project", fullPath: "team/project"},
+		},
+		"ApprovedIgnoringCase": {
+			reason: "Full paths should be approved regardless of their case, like GitLab does.",
+			args:   args{annotation: "Team/Project", fullPath: "team/project"},
+		},
+		"OtherPathApproved": {
+			reason: "Imports of a full path not listed should be pending.",
+			args:   args{annotation: "team/project", fullPath: "team/project-copy"},
+			want:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := NewApproval(tc.args.annotation).Check(Subject{Result: importer.Result{ExternalName: "42", FullPath: tc.args.fullPath}})
+			if diff := cmp.Diff(tc.want, IsPending(err)); diff != "" {
+				t.Errorf("%s\nCheck(...): -want pending, +got pending:\n%s\n%v", tc.reason, diff, err)
+			}
+		})
+	}
+}
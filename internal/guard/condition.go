@@ -0,0 +1,78 @@
+package guard
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/policy"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// Condition is a Guard evaluating a CEL expression against the external
+// resource, available as gitlab, and the desired composed resource,
+// available as desired.
+type Condition struct {
+	selector   v1beta1.ResourceSelector
+	expression string
+	message    string
+	program    cel.Program
+}
+
+// NewCondition compiles the expression of an import condition. The
+// expression has to return a bool.
+func NewCondition(c v1beta1.ImportCondition) (*Condition, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("gitlab", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("desired", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, errors.Errorf("cannot create cel environment: %w", err)
+	}
+	ast, iss := env.Compile(c.Expression)
+	if iss.Err() != nil {
+		return nil, errors.Errorf("cannot compile expression %q: %w", c.Expression, iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, errors.Errorf("expression %q returns %s instead of bool", c.Expression, ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, errors.Errorf("cannot create program for expression %q: %w", c.Expression, err)
+	}
+	return &Condition{selector: c.ResourceSelector, expression: c.Expression, message: c.Message, program: program}, nil
+}
+
+// Check blocks the import if the expression is false or cannot be evaluated.
+// Subjects not selected by the condition are allowed.
+func (c *Condition) Check(s Subject) error {
+	if policy.Select(c.selector, s.GVK, s.Name) == policy.NoMatch {
+		return nil
+	}
+	object := s.Object
+	if object == nil {
+		object = map[string]any{}
+	}
+	out, _, err := c.program.Eval(map[string]any{
+		"gitlab":  object,
+		"desired": s.Desired.Resource.Object,
+	})
+	if err != nil {
+		return Blocked("condition %q cannot be evaluated: %v", c.expression, err)
+	}
+	allowed, ok := out.Value().(bool)
+	if !ok {
+		return Blocked("condition %q returned %v instead of bool", c.expression, out.Value())
+	}
+	if allowed {
+		return nil
+	}
+	if c.message != "" {
+		return Blocked("%s", c.message)
+	}
+	return Blocked("condition %q is false", c.expression)
+}
+
+// NeedsObject returns true, as the expression inspects the external resource.
+func (c *Condition) NeedsObject() bool {
+	return true
+}
@@ -0,0 +1,114 @@
+package guard
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestCondition(t *testing.T) {
+	projectGVK := schema.GroupVersionKind{Group: "projects.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Project"}
+	desired := &resource.DesiredComposed{Resource: &composed.Unstructured{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind":       "Project",
+		"spec": map[string]any{
+			"forProvider": map[string]any{"visibility": "private"},
+		},
+	}}}}
+
+	type args struct {
+		condition v1beta1.ImportCondition
+		object    map[string]any
+	}
+	type want struct {
+		compileErr bool
+		blocked    string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"True": {
+			reason: "A true expression should allow the import.",
+			args: args{
+				condition: v1beta1.ImportCondition{Expression: `!gitlab.archived`},
+				object:    map[string]any{"archived": false},
+			},
+		},
+		"FalseWithoutMessage": {
+			reason: "A false expression should block the import naming the expression.",
+			args: args{
+				condition: v1beta1.ImportCondition{Expression: `!gitlab.archived`},
+				object:    map[string]any{"archived": true},
+			},
+			want: want{blocked: `condition "!gitlab.archived" is false`},
+		},
+		"FalseWithMessage": {
+			reason: "A false expression should block the import with the configured message.",
+			args: args{
+				condition: v1beta1.ImportCondition{
+					Expression: `gitlab.visibility == desired.spec.forProvider.visibility`,
+					Message:    "visibility does not match",
+				},
+				object: map[string]any{"visibility": "public"},
+			},
+			want: want{blocked: "visibility does not match"},
+		},
+		"NotSelected": {
+			reason: "Resources not selected by the condition should not be checked.",
+			args: args{
+				condition: v1beta1.ImportCondition{
+					ResourceSelector: v1beta1.ResourceSelector{Kind: "Group"},
+					Expression:       `false`,
+				},
+			},
+		},
+		"MissingField": {
+			reason: "An expression that cannot be evaluated should block the import.",
+			args: args{
+				condition: v1beta1.ImportCondition{Expression: `gitlab.archived`},
+				object:    map[string]any{},
+			},
+			want: want{blocked: `condition "gitlab.archived" cannot be evaluated: no such key: archived`},
+		},
+		"NotBool": {
+			reason: "An expression returning a string should not compile.",
+			args: args{
+				condition: v1beta1.ImportCondition{Expression: `"yes"`},
+			},
+			want: want{compileErr: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewCondition(tc.args.condition)
+			if diff := cmp.Diff(tc.want.compileErr, err != nil); diff != "" {
+				t.Fatalf("%s\nNewCondition(...): -want error, +got error:\n%s\n%v", tc.reason, diff, err)
+			}
+			if err != nil {
+				return
+			}
+
+			blocked := ""
+			err = c.Check(Subject{Name: "project", GVK: projectGVK, Desired: desired, Object: tc.args.object})
+			if err != nil {
+				if !IsBlocked(err) {
+					t.Fatalf("%s\nc.Check(...): want BlockedError, got %v", tc.reason, err)
+				}
+				blocked = err.Error()
+			}
+			if diff := cmp.Diff(tc.want.blocked, blocked); diff != "" {
+				t.Errorf("%s\nc.Check(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
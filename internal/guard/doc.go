@@ -0,0 +1,8 @@
+// Package guard decides whether an existing external resource may be
+// imported into a desired composed resource.
+//
+// Guards are configured by the Function input. They are checked after the
+// external resource has been looked up and before its external-name is set.
+// A guard that does not allow an import blocks it with a reason, which is
+// reported as result.
+package guard
@@ -0,0 +1,116 @@
+package guard
+
+import (
+	"fmt"
+
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Subject is an external resource about to be imported into a desired
+// composed resource.
+type Subject struct {
+	// Name is the composition resource name of the composed resource.
+	Name resource.Name
+	// GVK of the composed resource.
+	GVK schema.GroupVersionKind
+	// Desired is the desired composed resource.
+	Desired *resource.DesiredComposed
+	// Owner is the UID of the composite resource adopting the external
+	// resource.
+	Owner string
+	// Result describes the external resource found by the importer.
+	Result importer.Result
+	// Object is the external resource as returned by the provider's API. It
+	// is only set if a guard needs it.
+	Object map[string]any
+}
+
+// BlockedError is returned by guards that do not allow an import.
+type BlockedError struct {
+	// Reason the import is blocked for.
+	Reason string
+}
+
+// Error returns the reason the import is blocked for.
+func (e *BlockedError) Error() string {
+	return e.Reason
+}
+
+// Blocked returns a BlockedError with the formatted reason.
+func Blocked(format string, args ...any) error {
+	return &BlockedError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// IsBlocked returns true if err is or wraps a BlockedError.
+func IsBlocked(err error) bool {
+	var b *BlockedError
+	return errors.As(err, &b)
+}
+
+// A Guard decides whether an external resource may be imported.
+type Guard interface {
+	// Check returns a BlockedError if the subject may not be imported. Other
+	// errors are returned if the check could not be made.
+	Check(s Subject) error
+
+	// NeedsObject returns true if the guard inspects Subject.Object.
+	NeedsObject() bool
+}
+
+// Guards checks every subject against multiple guards.
+type Guards []Guard
+
+// Check returns the error of the first guard that does not allow the import
+// of the subject.
+func (gs Guards) Check(s Subject) error {
+	for _, g := range gs {
+		if err := g.Check(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NeedsObject returns true if any guard inspects Subject.Object.
+func (gs Guards) NeedsObject() bool {
+	for _, g := range gs {
+		if g.NeedsObject() {
+			return true
+		}
+	}
+	return false
+}
+
+// FromInput returns all guards configured by the Function input.
+func FromInput(in *v1beta1.Input) (Guards, error) {
+	gs := Guards{}
+	// the path filter does not need the external resource, so it is checked
+	// first
+	if len(in.ImportAllow) > 0 || len(in.ImportDeny) > 0 {
+		f, err := NewPathFilter(in.ImportAllow, in.ImportDeny)
+		if err != nil {
+			return nil, err
+		}
+		gs = append(gs, f)
+	}
+	if in.Ownership != nil {
+		o, err := NewOwnership(*in.Ownership)
+		if err != nil {
+			return nil, errors.Errorf("invalid ownership: %w", err)
+		}
+		gs = append(gs, o)
+	}
+	for i, c := range in.ImportConditions {
+		g, err := NewCondition(c)
+		if err != nil {
+			return nil, errors.Errorf("invalid import condition %d: %w", i, err)
+		}
+		gs = append(gs, g)
+	}
+	return gs, nil
+}
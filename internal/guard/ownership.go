@@ -0,0 +1,155 @@
+package guard
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/policy"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// DefaultOwnerKey is the key of owner markers if the input does not set one.
+const DefaultOwnerKey = "crossplane.io/owner"
+
+// OwnerMarker returns the topic and description marker naming the owner
+// with the given UID, e.g. crossplane.io/owner=<uid>.
+func OwnerMarker(key, uid string) string {
+	return importer.Marker{Key: key, Value: uid}.String()
+}
+
+// uidMapping is a compiled v1beta1.UIDMapping.
+type uidMapping struct {
+	uid      string
+	patterns []*Pattern
+}
+
+// Ownership is a Guard requiring markers on the external resource proving
+// that it belongs to the composite resource adopting it.
+type Ownership struct {
+	key      string
+	markers  []v1beta1.OwnershipMarker
+	mappings []uidMapping
+}
+
+// NewOwnership compiles the ownership configuration of the input.
+func NewOwnership(o v1beta1.Ownership) (*Ownership, error) {
+	g := &Ownership{key: o.Key, markers: o.Markers}
+	if g.key == "" {
+		g.key = DefaultOwnerKey
+	}
+	for i, m := range o.Markers {
+		switch m.Type {
+		case v1beta1.OwnershipMarkerTopic, v1beta1.OwnershipMarkerCustomAttribute, v1beta1.OwnershipMarkerDescription, v1beta1.OwnershipMarkerUIDMapping:
+		default:
+			return nil, errors.Errorf("marker %d has unknown type %q", i, m.Type)
+		}
+	}
+	for i, m := range o.UIDMappings {
+		mapping := uidMapping{uid: m.UID}
+		for j, p := range m.Patterns {
+			compiled, err := NewPattern(p)
+			if err != nil {
+				return nil, errors.Errorf("invalid pattern %d of uid mapping %d: %w", j, i, err)
+			}
+			mapping.patterns = append(mapping.patterns, compiled)
+		}
+		g.mappings = append(g.mappings, mapping)
+	}
+	return g, nil
+}
+
+// Check blocks the import if any marker names another owner, as the external
+// resource has been adopted by another composite resource then. Otherwise it
+// blocks the import unless every marker selecting the subject is present.
+// All missing markers are named in the reason.
+func (g *Ownership) Check(s Subject) error {
+	if s.Owner == "" {
+		return Blocked("ownership cannot be verified: composite resource has no uid")
+	}
+	if other := g.foreignOwner(s); other != "" {
+		return Blocked("%s is already owned by %s", s.Result.FullPath, other)
+	}
+	missing := []string{}
+	for _, m := range g.markers {
+		if policy.Select(m.ResourceSelector, s.GVK, s.Name) == policy.NoMatch {
+			continue
+		}
+		if !g.present(m.Type, s) {
+			missing = append(missing, string(m.Type))
+		}
+	}
+	if len(missing) > 0 {
+		return Blocked("ownership of %s by %s not verified: missing %s", s.Result.FullPath, s.Owner, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// present returns true if the subject carries a marker of the given type.
+func (g *Ownership) present(t v1beta1.OwnershipMarkerType, s Subject) bool {
+	switch t {
+	case v1beta1.OwnershipMarkerTopic:
+		topics, _ := s.Object["topics"].([]any)
+		return slices.Contains(topics, any(OwnerMarker(g.key, s.Owner)))
+	case v1beta1.OwnershipMarkerCustomAttribute:
+		attributes, _ := s.Object["custom_attributes"].([]any)
+		for _, a := range attributes {
+			attribute, _ := a.(map[string]any)
+			if attribute["key"] == g.key && attribute["value"] == s.Owner {
+				return true
+			}
+		}
+		return false
+	case v1beta1.OwnershipMarkerDescription:
+		description, _ := s.Object["description"].(string)
+		return strings.Contains(description, OwnerMarker(g.key, s.Owner))
+	case v1beta1.OwnershipMarkerUIDMapping:
+		for _, m := range g.mappings {
+			if m.uid != s.Owner {
+				continue
+			}
+			for _, p := range m.patterns {
+				if p.Match(s.Result.FullPath, s.Result.NamespaceID) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// foreignOwner returns the first owner other than the subject's owner named
+// by a topic, custom attribute or description marker of the subject.
+func (g *Ownership) foreignOwner(s Subject) string {
+	prefix := OwnerMarker(g.key, "")
+	topics, _ := s.Object["topics"].([]any)
+	for _, t := range topics {
+		topic, _ := t.(string)
+		if owner, ok := strings.CutPrefix(topic, prefix); ok && owner != s.Owner {
+			return owner
+		}
+	}
+	attributes, _ := s.Object["custom_attributes"].([]any)
+	for _, a := range attributes {
+		attribute, _ := a.(map[string]any)
+		if owner, _ := attribute["value"].(string); attribute["key"] == g.key && owner != s.Owner {
+			return owner
+		}
+	}
+	description, _ := s.Object["description"].(string)
+	for _, field := range strings.Fields(description) {
+		if owner, ok := strings.CutPrefix(field, prefix); ok && owner != s.Owner {
+			return owner
+		}
+	}
+	return ""
+}
+
+// NeedsObject returns true, as the markers of other owners are looked for
+// on the external resource.
+func (g *Ownership) NeedsObject() bool {
+	return true
+}
@@ -0,0 +1,129 @@
+package guard
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestOwnership(t *testing.T) {
+	projectGVK := schema.GroupVersionKind{Group: "projects.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Project"}
+	result := importer.Result{FullPath: "team/project", NamespaceID: 1}
+
+	type args struct {
+		ownership v1beta1.Ownership
+		owner     string
+		object    map[string]any
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"Topic": {
+			reason: "A topic naming the owner should verify the ownership.",
+			args: args{
+				ownership: v1beta1.Ownership{Markers: []v1beta1.OwnershipMarker{{Type: v1beta1.OwnershipMarkerTopic}}},
+				owner:     "uid",
+				object:    map[string]any{"topics": []any{"backend", "crossplane.io/owner=uid"}},
+			},
+		},
+		"TopicOfOtherOwner": {
+			reason: "A resource whose topic names another owner should be refused.",
+			args: args{
+				ownership: v1beta1.Ownership{Markers: []v1beta1.OwnershipMarker{{Type: v1beta1.OwnershipMarkerTopic}}},
+				owner:     "uid",
+				object:    map[string]any{"topics": []any{"crossplane.io/owner=other"}},
+			},
+			want: "team/project is already owned by other",
+		},
+		"DescriptionOfOtherOwner": {
+			reason: "A resource whose description names another owner should be refused, even if no marker is required.",
+			args: args{
+				ownership: v1beta1.Ownership{},
+				owner:     "uid",
+				object:    map[string]any{"description": "managed by crossplane\ncrossplane.io/owner=other"},
+			},
+			want: "team/project is already owned by other",
+		},
+		"CustomAttributeOfOtherOwner": {
+			reason: "A resource whose custom attribute names another owner should be refused.",
+			args: args{
+				ownership: v1beta1.Ownership{Markers: []v1beta1.OwnershipMarker{{Type: v1beta1.OwnershipMarkerUIDMapping}}},
+				owner:     "uid",
+				object:    map[string]any{"custom_attributes": []any{map[string]any{"key": "crossplane.io/owner", "value": "other"}}},
+			},
+			want: "team/project is already owned by other",
+		},
+		"CustomAttributeWithKey": {
+			reason: "A custom attribute with the configured key should verify the ownership.",
+			args: args{
+				ownership: v1beta1.Ownership{Key: "example.org/owner", Markers: []v1beta1.OwnershipMarker{{Type: v1beta1.OwnershipMarkerCustomAttribute}}},
+				owner:     "uid",
+				object:    map[string]any{"custom_attributes": []any{map[string]any{"key": "example.org/owner", "value": "uid"}}},
+			},
+		},
+		"AllMarkersRequired": {
+			reason: "Every missing marker should be reported.",
+			args: args{
+				ownership: v1beta1.Ownership{Markers: []v1beta1.OwnershipMarker{
+					{Type: v1beta1.OwnershipMarkerDescription},
+					{Type: v1beta1.OwnershipMarkerCustomAttribute},
+					{Type: v1beta1.OwnershipMarkerUIDMapping},
+				}},
+				owner:  "uid",
+				object: map[string]any{"description": "owned by crossplane.io/owner=uid"},
+			},
+			want: "ownership of team/project by uid not verified: missing CustomAttribute, UIDMapping",
+		},
+		"UIDMapping": {
+			reason: "A UID mapping of the owner matching the resource should verify the ownership.",
+			args: args{
+				ownership: v1beta1.Ownership{
+					Markers: []v1beta1.OwnershipMarker{{Type: v1beta1.OwnershipMarkerUIDMapping}},
+					UIDMappings: []v1beta1.UIDMapping{
+						{UID: "other", Patterns: []v1beta1.ImportPattern{{FullPath: "**"}}},
+						{UID: "uid", Patterns: []v1beta1.ImportPattern{{FullPath: "team/*"}}},
+					},
+				},
+				owner: "uid",
+			},
+		},
+		"NotSelected": {
+			reason: "Markers not selecting the resource should not be required.",
+			args: args{
+				ownership: v1beta1.Ownership{Markers: []v1beta1.OwnershipMarker{
+					{ResourceSelector: v1beta1.ResourceSelector{Kind: "Group"}, Type: v1beta1.OwnershipMarkerTopic},
+				}},
+				owner: "uid",
+			},
+		},
+		"NoOwner": {
+			reason: "Ownership cannot be verified for a composite resource without UID.",
+			args: args{
+				ownership: v1beta1.Ownership{Markers: []v1beta1.OwnershipMarker{{Type: v1beta1.OwnershipMarkerTopic}}},
+			},
+			want: "ownership cannot be verified: composite resource has no uid",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o, err := NewOwnership(tc.args.ownership)
+			if err != nil {
+				t.Fatalf("%s\nNewOwnership(...): %v", tc.reason, err)
+			}
+			got := ""
+			if err := o.Check(Subject{Name: "project", GVK: projectGVK, Owner: tc.args.owner, Result: result, Object: tc.args.object}); err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\no.Check(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package guard
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// Pattern matches external resources by their full path and namespace ID.
+// Full paths are matched case-insensitively, as GitLab does not tell apart
+// paths differing in case only.
+type Pattern struct {
+	source       v1beta1.ImportPattern
+	fullPath     *regexp.Regexp
+	regex        *regexp.Regexp
+	namespaceIDs []int
+}
+
+// NewPattern compiles an import pattern. At least one of its fields has to
+// be set.
+func NewPattern(p v1beta1.ImportPattern) (*Pattern, error) {
+	if p.FullPath == "" && p.FullPathRegex == "" && len(p.NamespaceIDs) == 0 {
+		return nil, errors.New("pattern has to set fullPath, fullPathRegex or namespaceIds")
+	}
+	compiled := &Pattern{source: p, namespaceIDs: p.NamespaceIDs}
+	if p.FullPath != "" {
+		compiled.fullPath = regexp.MustCompile(globToRegex(p.FullPath))
+	}
+	if p.FullPathRegex != "" {
+		re, err := regexp.Compile("(?i)^(?:" + p.FullPathRegex + ")$")
+		if err != nil {
+			return nil, errors.Errorf("cannot compile fullPathRegex %q: %w", p.FullPathRegex, err)
+		}
+		compiled.regex = re
+	}
+	return compiled, nil
+}
+
+// Match returns true if the full path and the namespace ID match all fields
+// set by the pattern.
+func (p *Pattern) Match(fullPath string, namespaceID int) bool {
+	if p.fullPath != nil && !p.fullPath.MatchString(fullPath) {
+		return false
+	}
+	if p.regex != nil && !p.regex.MatchString(fullPath) {
+		return false
+	}
+	if len(p.namespaceIDs) > 0 && !slices.Contains(p.namespaceIDs, namespaceID) {
+		return false
+	}
+	return true
+}
+
+// String returns the fields set by the pattern, e.g. fullPath=security/*.
+func (p *Pattern) String() string {
+	fields := []string{}
+	if p.source.FullPath != "" {
+		fields = append(fields, "fullPath="+p.source.FullPath)
+	}
+	if p.source.FullPathRegex != "" {
+		fields = append(fields, "fullPathRegex="+p.source.FullPathRegex)
+	}
+	if len(p.source.NamespaceIDs) > 0 {
+		fields = append(fields, fmt.Sprintf("namespaceIds=%v", p.source.NamespaceIDs))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// globToRegex translates a glob on full paths to an anchored, case-insensitive
+// regular expression. * and ? do not match the path separator, ** matches
+// anything.
+func globToRegex(glob string) string {
+	var re strings.Builder
+	re.WriteString("(?i)^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			re.WriteString("[^/]*")
+		case glob[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(glob[i])))
+		}
+	}
+	re.WriteString("$")
+	return re.String()
+}
+
+// PathFilter is a Guard allowing and denying imports by the location of the
+// external resource. Deny patterns take precedence over allow patterns.
+type PathFilter struct {
+	allow []*Pattern
+	deny  []*Pattern
+}
+
+// NewPathFilter compiles the allow and deny patterns.
+func NewPathFilter(allow, deny []v1beta1.ImportPattern) (*PathFilter, error) {
+	f := &PathFilter{}
+	for i, p := range allow {
+		compiled, err := NewPattern(p)
+		if err != nil {
+			return nil, errors.Errorf("invalid allow pattern %d: %w", i, err)
+		}
+		f.allow = append(f.allow, compiled)
+	}
+	for i, p := range deny {
+		compiled, err := NewPattern(p)
+		if err != nil {
+			return nil, errors.Errorf("invalid deny pattern %d: %w", i, err)
+		}
+		f.deny = append(f.deny, compiled)
+	}
+	return f, nil
+}
+
+// Check blocks the import if the external resource matches a deny pattern
+// or, if there are allow patterns, does not match any of them.
+func (f *PathFilter) Check(s Subject) error {
+	for _, p := range f.deny {
+		if p.Match(s.Result.FullPath, s.Result.NamespaceID) {
+			return Blocked("%s matches deny pattern %s", s.Result.FullPath, p)
+		}
+	}
+	if len(f.allow) == 0 {
+		return nil
+	}
+	for _, p := range f.allow {
+		if p.Match(s.Result.FullPath, s.Result.NamespaceID) {
+			return nil
+		}
+	}
+	return Blocked("%s does not match any allow pattern", s.Result.FullPath)
+}
+
+// NeedsObject returns false, as the location is part of the lookup result.
+func (f *PathFilter) NeedsObject() bool {
+	return false
+}
@@ -0,0 +1,117 @@
+package guard
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+)
+
+func TestPathFilter(t *testing.T) {
+	type args struct {
+		allow       []v1beta1.ImportPattern
+		deny        []v1beta1.ImportPattern
+		fullPath    string
+		namespaceID int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"NoPatterns": {
+			reason: "Without patterns every resource should be allowed.",
+			args:   args{fullPath: "team-a/project"},
+		},
+		"AllowSubtree": {
+			reason: "** should match any number of path segments.",
+			args: args{
+				allow:    []v1beta1.ImportPattern{{FullPath: "team-a/**"}},
+				fullPath: "team-a/sub/project",
+			},
+		},
+		"NotAllowed": {
+			reason: "A resource outside of all allow patterns should be blocked.",
+			args: args{
+				allow:    []v1beta1.ImportPattern{{FullPath: "team-a/**"}},
+				fullPath: "team-b/project",
+			},
+			want: "team-b/project does not match any allow pattern",
+		},
+		"SingleSegment": {
+			reason: "* should not match the path separator.",
+			args: args{
+				allow:    []v1beta1.ImportPattern{{FullPath: "team-a/*"}},
+				fullPath: "team-a/sub/project",
+			},
+			want: "team-a/sub/project does not match any allow pattern",
+		},
+		"DenyBeatsAllow": {
+			reason: "A deny pattern should block a resource even if it is allowed.",
+			args: args{
+				allow:    []v1beta1.ImportPattern{{FullPathRegex: ".*"}},
+				deny:     []v1beta1.ImportPattern{{FullPath: "security/*"}},
+				fullPath: "security/keys",
+			},
+			want: "security/keys matches deny pattern fullPath=security/*",
+		},
+		"DenyIgnoresCase": {
+			reason: "A deny pattern should block a resource whose full path differs in case only.",
+			args: args{
+				deny:     []v1beta1.ImportPattern{{FullPath: "security/**"}},
+				fullPath: "Security/keys",
+			},
+			want: "Security/keys matches deny pattern fullPath=security/**",
+		},
+		"DenyRegexIgnoresCase": {
+			reason: "A deny regex should block a resource whose full path differs in case only.",
+			args: args{
+				deny:     []v1beta1.ImportPattern{{FullPathRegex: "security/.*"}},
+				fullPath: "SECURITY/Keys",
+			},
+			want: "SECURITY/Keys matches deny pattern fullPathRegex=security/.*",
+		},
+		"RegexIsAnchored": {
+			reason: "A regex should have to match the whole full path.",
+			args: args{
+				deny:     []v1beta1.ImportPattern{{FullPathRegex: "team-(a|b)"}},
+				fullPath: "team-a/project",
+			},
+		},
+		"NamespaceID": {
+			reason: "A pattern should match resources within the given namespaces.",
+			args: args{
+				allow:       []v1beta1.ImportPattern{{NamespaceIDs: []int{1, 2}}},
+				fullPath:    "team/project",
+				namespaceID: 3,
+			},
+			want: "team/project does not match any allow pattern",
+		},
+		"AllFieldsMatch": {
+			reason: "A pattern should only match if all of its fields match.",
+			args: args{
+				deny:        []v1beta1.ImportPattern{{FullPath: "team/**", NamespaceIDs: []int{1}}},
+				fullPath:    "team/project",
+				namespaceID: 2,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := NewPathFilter(tc.args.allow, tc.args.deny)
+			if err != nil {
+				t.Fatalf("%s\nNewPathFilter(...): %v", tc.reason, err)
+			}
+			got := ""
+			if err := f.Check(Subject{Result: importer.Result{FullPath: tc.args.fullPath, NamespaceID: tc.args.namespaceID}}); err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nf.Check(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
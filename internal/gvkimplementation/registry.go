@@ -16,14 +16,18 @@ type Implementation struct {
 	Importer importer.Importer
 }
 
-var implementationByGVK = map[schema.GroupVersionKind]Implementation{
-	providergroupsv1alpha1.GroupKubernetesGroupVersionKind: {
-		Handler:  &gitlabhandler.GroupHandler{},
-		Importer: &gitlabimporter.GroupImporter{},
+var implementationByGVK = map[schema.GroupVersionKind]func() Implementation{
+	providergroupsv1alpha1.GroupKubernetesGroupVersionKind: func() Implementation {
+		return Implementation{
+			Handler:  &gitlabhandler.GroupHandler{},
+			Importer: &gitlabimporter.GroupImporter{},
+		}
 	},
-	providerprojectsv1alpha1.ProjectGroupVersionKind: {
-		Handler:  &gitlabhandler.ProjectHandler{},
-		Importer: &gitlabimporter.ProjectImporter{},
+	providerprojectsv1alpha1.ProjectGroupVersionKind: func() Implementation {
+		return Implementation{
+			Handler:  &gitlabhandler.ProjectHandler{},
+			Importer: &gitlabimporter.ProjectImporter{},
+		}
 	},
 }
 
@@ -43,7 +47,13 @@ func IsAllowed(gvk schema.GroupVersionKind) bool {
 // LookupByGKV retrieves the implementation (handler and importer) associated
 // with the given GroupVersionKind (GVK) from the implementationByGVK registry.
 // It returns the implementation and a boolean indicating whether the GVK was found.
+//
+// Every call returns a new implementation, so importers can keep state for the
+// duration of a single request without sharing it with other requests.
 func LookupByGKV(gkv schema.GroupVersionKind) (Implementation, bool) {
-	i, ok := implementationByGVK[gkv]
-	return i, ok
+	newImplementation, ok := implementationByGVK[gkv]
+	if !ok {
+		return Implementation{}, false
+	}
+	return newImplementation(), true
 }
@@ -0,0 +1,157 @@
+package gitlabimporter
+
+import (
+	"context"
+	"sort"
+
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/tracing"
+)
+
+// batch collects the paths of all pending imports of one kind within a single
+// request, grouped by the namespace they live in. Every namespace is listed at
+// most once per request, no matter how many of the pending imports it holds.
+type batch[T any] struct {
+	paths    map[int]map[string]struct{}
+	resolved map[int][]string
+	listings map[int][]T
+	errs     map[int]error
+}
+
+// plan registers path as pending import within the namespace namespaceID.
+func (b *batch[T]) plan(namespaceID int, path string) {
+	if b.paths == nil {
+		b.paths = map[int]map[string]struct{}{}
+	}
+	if b.paths[namespaceID] == nil {
+		b.paths[namespaceID] = map[string]struct{}{}
+	}
+	b.paths[namespaceID][path] = struct{}{}
+}
+
+// namespaces returns the IDs of all planned namespaces in ascending order.
+func (b *batch[T]) namespaces() []int {
+	namespaceIDs := make([]int, 0, len(b.paths))
+	for namespaceID := range b.paths {
+		namespaceIDs = append(namespaceIDs, namespaceID)
+	}
+	sort.Ints(namespaceIDs)
+	return namespaceIDs
+}
+
+// resolve looks up every planned namespace that has not been resolved yet.
+// The lookup receives the planned paths of these namespaces and returns the
+// matching items per namespace. Errors are kept per namespace, so a failing
+// namespace does not affect the others. Items returned along with an error,
+// such as an incomplete listing, are kept as well.
+func (b *batch[T]) resolve(ctx context.Context, lookup func(ctx context.Context, pending map[int][]string) (map[int][]T, map[int]error)) {
+	if b.listings == nil {
+		b.listings = map[int][]T{}
+	}
+	if b.errs == nil {
+		b.errs = map[int]error{}
+	}
+	if b.resolved == nil {
+		b.resolved = map[int][]string{}
+	}
+
+	pending := map[int][]string{}
+	for _, namespaceID := range b.namespaces() {
+		if _, ok := b.listings[namespaceID]; ok {
+			continue
+		}
+		paths := make([]string, 0, len(b.paths[namespaceID]))
+		for path := range b.paths[namespaceID] {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		pending[namespaceID] = paths
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	listings, errs := lookup(ctx, pending)
+	for namespaceID := range pending {
+		if err, ok := errs[namespaceID]; ok {
+			b.errs[namespaceID] = err
+		}
+		b.listings[namespaceID] = listings[namespaceID]
+		b.resolved[namespaceID] = pending[namespaceID]
+	}
+}
+
+// perNamespace returns a lookup for resolve that lists every pending namespace
+// on its own using list. Besides the namespace, list receives the pending
+// paths within it to narrow down the listing. Every listing is traced within
+// its own span.
+func perNamespace[T any](list func(ctx context.Context, namespaceID int, paths []string) ([]T, error)) func(ctx context.Context, pending map[int][]string) (map[int][]T, map[int]error) {
+	return func(ctx context.Context, pending map[int][]string) (map[int][]T, map[int]error) {
+		listings := map[int][]T{}
+		errs := map[int]error{}
+		for namespaceID, paths := range pending {
+			ctx, span := tracing.Start(ctx, "ListNamespace", tracing.AttrNamespaceID.Int(namespaceID))
+			items, err := list(ctx, namespaceID, paths)
+			tracing.End(span, err)
+			if err != nil {
+				errs[namespaceID] = err
+			}
+			listings[namespaceID] = items
+		}
+		return listings, errs
+	}
+}
+
+// listing returns the resolved listing of the namespace namespaceID and the
+// error that occurred while resolving it, if any.
+func (b *batch[T]) listing(namespaceID int) ([]T, error) {
+	return b.listings[namespaceID], b.errs[namespaceID]
+}
+
+// cached reports whether the namespace namespaceID has been resolved by an
+// earlier import already.
+func (b *batch[T]) cached(namespaceID int) bool {
+	_, ok := b.listings[namespaceID]
+	return ok
+}
+
+// resolvedPaths returns the paths of the namespace namespaceID that were
+// pending when the namespace has been resolved.
+func (b *batch[T]) resolvedPaths(namespaceID int) []string {
+	return b.resolved[namespaceID]
+}
+
+// minSearchLength is the minimum length of a search term accepted by GitLab.
+const minSearchLength = 3
+
+// searchTerm returns a term which narrows down the listing of a namespace to
+// the given paths on the server. This is only possible for a single path long
+// enough to be searched for, otherwise the whole namespace has to be listed.
+// The search matches names and paths partially, so the listing still has to
+// be matched locally.
+func searchTerm(paths []string) string {
+	if len(paths) == 1 && len(paths[0]) >= minSearchLength {
+		return paths[0]
+	}
+	return ""
+}
+
+// Lookup strategies reported with every import result.
+const (
+	strategyRESTList   = "REST/list"
+	strategyRESTSearch = "REST/search"
+	strategyGraphQL    = "GraphQL/fullPath"
+)
+
+// lookupStrategy returns the strategy used by backend to look up the given
+// paths of a namespace.
+func lookupStrategy(backend v1beta1.LookupBackend, paths []string) string {
+	switch {
+	case backend == v1beta1.LookupBackendGraphQL:
+		return strategyGraphQL
+	case searchTerm(paths) != "":
+		return strategyRESTSearch
+	default:
+		return strategyRESTList
+	}
+}
@@ -7,4 +7,6 @@
 //   - ProjectImporter: Handles importing GitLab projects by locating a project within a parent group.
 //
 // These importers use the GitLab API client to query resources and support pagination for large datasets.
+// Pending imports are planned per request and grouped by namespace, so every namespace is listed
+// only once, no matter how many resources of a request it contains.
 package gitlabimporter
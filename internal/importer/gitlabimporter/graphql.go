@@ -0,0 +1,211 @@
+package gitlabimporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// graphQLBatchSize is the maximum number of IDs or full paths resolved within
+// a single GraphQL query. It keeps queries below GitLab's complexity limit.
+const graphQLBatchSize = 50
+
+// graphQLNode holds the fields queried for every group and project.
+type graphQLNode struct {
+	ID       string `json:"id"`
+	Path     string `json:"path"`
+	FullPath string `json:"fullPath"`
+	WebURL   string `json:"webUrl"`
+}
+
+// graphQLResponse is the body of a GraphQL response. GitLab reports errors of
+// a query with status 200, so they have to be checked in the body.
+type graphQLResponse[T any] struct {
+	Data   T                          `json:"data"`
+	Errors []struct{ Message string } `json:"errors"`
+}
+
+// err returns the errors of the response as a single error.
+func (r graphQLResponse[T]) err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		messages = append(messages, e.Message)
+	}
+	return errors.Errorf("graphql query failed: %s", strings.Join(messages, "; "))
+}
+
+// getGroupsGraphQL looks up the pending subgroups of all namespaces using the
+// GraphQL API. It needs one query to resolve the full paths of the namespaces
+// and one query per graphQLBatchSize pending subgroups.
+func getGroupsGraphQL(ctx context.Context, client *gitlab.Client, pending map[int][]string) (map[int][]*gitlab.Group, map[int]error) {
+	nodes, errs := resolveGraphQL(ctx, client, "group", pending)
+	groups := map[int][]*gitlab.Group{}
+	for namespaceID, n := range nodes {
+		for _, node := range n {
+			id, err := parseGlobalID(node.ID)
+			if err != nil {
+				errs[namespaceID] = err
+				break
+			}
+			groups[namespaceID] = append(groups[namespaceID], &gitlab.Group{
+				ID:       id,
+				Path:     node.Path,
+				FullPath: node.FullPath,
+				WebURL:   node.WebURL,
+			})
+		}
+	}
+	return groups, errs
+}
+
+// getProjectsGraphQL looks up the pending projects of all namespaces using the
+// GraphQL API. It needs one query to resolve the full paths of the namespaces
+// and one query per graphQLBatchSize pending projects.
+func getProjectsGraphQL(ctx context.Context, client *gitlab.Client, pending map[int][]string) (map[int][]*gitlab.Project, map[int]error) {
+	nodes, errs := resolveGraphQL(ctx, client, "project", pending)
+	projects := map[int][]*gitlab.Project{}
+	for namespaceID, n := range nodes {
+		for _, node := range n {
+			id, err := parseGlobalID(node.ID)
+			if err != nil {
+				errs[namespaceID] = err
+				break
+			}
+			projects[namespaceID] = append(projects[namespaceID], &gitlab.Project{
+				ID:                id,
+				Path:              node.Path,
+				PathWithNamespace: node.FullPath,
+				WebURL:            node.WebURL,
+			})
+		}
+	}
+	return projects, errs
+}
+
+// resolveGraphQL resolves the pending paths of all namespaces to nodes of the
+// given field, which is either "group" or "project". Paths that do not exist
+// are left out of the result.
+func resolveGraphQL(ctx context.Context, client *gitlab.Client, field string, pending map[int][]string) (map[int][]graphQLNode, map[int]error) {
+	nodes := map[int][]graphQLNode{}
+	errs := map[int]error{}
+
+	namespaceIDs := make([]int, 0, len(pending))
+	for namespaceID := range pending {
+		namespaceIDs = append(namespaceIDs, namespaceID)
+	}
+	sort.Ints(namespaceIDs)
+
+	fullPaths, err := getNamespaceFullPaths(ctx, client, namespaceIDs)
+	if err != nil {
+		for _, namespaceID := range namespaceIDs {
+			errs[namespaceID] = err
+		}
+		return nodes, errs
+	}
+
+	// collect the full paths of all pending resources
+	type candidate struct {
+		namespaceID int
+		fullPath    string
+	}
+	candidates := []candidate{}
+	for _, namespaceID := range namespaceIDs {
+		namespacePath, ok := fullPaths[namespaceID]
+		if !ok {
+			errs[namespaceID] = errors.Errorf("there is no group with id: %d", namespaceID)
+			continue
+		}
+		for _, path := range pending[namespaceID] {
+			candidates = append(candidates, candidate{namespaceID: namespaceID, fullPath: namespacePath + "/" + path})
+		}
+	}
+
+	for start := 0; start < len(candidates); start += graphQLBatchSize {
+		chunk := candidates[start:min(start+graphQLBatchSize, len(candidates))]
+
+		// query every candidate using its own alias
+		var query strings.Builder
+		query.WriteString("query {")
+		for i, c := range chunk {
+			fmt.Fprintf(&query, " r%d: %s(fullPath: %s) { id path fullPath webUrl }", i, field, quoteGraphQL(c.fullPath))
+		}
+		query.WriteString(" }")
+
+		rsp := graphQLResponse[map[string]*graphQLNode]{}
+		_, err := client.GraphQL.Do(gitlab.GraphQLQuery{Query: query.String()}, &rsp, gitlab.WithContext(ctx))
+		if err == nil {
+			err = rsp.err()
+		}
+		for i, c := range chunk {
+			if err != nil {
+				errs[c.namespaceID] = errors.Errorf("cannot resolve %s %s: %w", field, c.fullPath, err)
+				continue
+			}
+			if node := rsp.Data["r"+strconv.Itoa(i)]; node != nil {
+				nodes[c.namespaceID] = append(nodes[c.namespaceID], *node)
+			}
+		}
+	}
+	return nodes, errs
+}
+
+// getNamespaceFullPaths returns the full paths of the groups with the given IDs.
+func getNamespaceFullPaths(ctx context.Context, client *gitlab.Client, namespaceIDs []int) (map[int]string, error) {
+	fullPaths := map[int]string{}
+	for start := 0; start < len(namespaceIDs); start += graphQLBatchSize {
+		chunk := namespaceIDs[start:min(start+graphQLBatchSize, len(namespaceIDs))]
+
+		ids := make([]string, 0, len(chunk))
+		for _, namespaceID := range chunk {
+			ids = append(ids, quoteGraphQL(fmt.Sprintf("gid://gitlab/Group/%d", namespaceID)))
+		}
+		query := fmt.Sprintf("query { groups(ids: [%s], first: %d) { nodes { id fullPath } } }", strings.Join(ids, ", "), len(chunk))
+
+		rsp := graphQLResponse[struct {
+			Groups struct {
+				Nodes []graphQLNode `json:"nodes"`
+			} `json:"groups"`
+		}]{}
+		if _, err := client.GraphQL.Do(gitlab.GraphQLQuery{Query: query}, &rsp, gitlab.WithContext(ctx)); err != nil {
+			return nil, errors.Errorf("cannot resolve namespaces: %w", err)
+		}
+		if err := rsp.err(); err != nil {
+			return nil, errors.Errorf("cannot resolve namespaces: %w", err)
+		}
+		for _, node := range rsp.Data.Groups.Nodes {
+			id, err := parseGlobalID(node.ID)
+			if err != nil {
+				return nil, err
+			}
+			fullPaths[id] = node.FullPath
+		}
+	}
+	return fullPaths, nil
+}
+
+// parseGlobalID returns the numeric ID of a GraphQL global ID such as
+// "gid://gitlab/Project/42".
+func parseGlobalID(gid string) (int, error) {
+	id, err := strconv.Atoi(gid[strings.LastIndex(gid, "/")+1:])
+	if err != nil {
+		return -1, errors.Errorf("cannot parse global id %q: %w", gid, err)
+	}
+	return id, nil
+}
+
+// quoteGraphQL returns s as GraphQL string literal. JSON strings are valid
+// GraphQL strings, so encoding/json takes care of all escaping.
+func quoteGraphQL(s string) string {
+	b, _ := json.Marshal(s) //nolint:errchkjson // marshaling a string cannot fail
+	return string(b)
+}
@@ -0,0 +1,161 @@
+package gitlabimporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+var (
+	graphQLGroupIDs = regexp.MustCompile(`"gid://gitlab/Group/(\d+)"`)
+	graphQLAliases  = regexp.MustCompile(`(r\d+): (group|project)\(fullPath: "([^"]+)"\)`)
+)
+
+// newGraphQLServer returns a stand-in GitLab GraphQL API. It knows the full
+// paths of the given groups and resolves the given resources by full path.
+// It counts the queries it received.
+func newGraphQLServer(t *testing.T, groups map[string]string, resources map[string]graphQLNode) (*gitlab.Client, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	queries := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			http.NotFound(w, r)
+			return
+		}
+		q := gitlab.GraphQLQuery{}
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		queries++
+		mu.Unlock()
+
+		data := map[string]any{}
+		if ids := graphQLGroupIDs.FindAllStringSubmatch(q.Query, -1); ids != nil {
+			nodes := []graphQLNode{}
+			for _, id := range ids {
+				if fullPath, ok := groups[id[1]]; ok {
+					nodes = append(nodes, graphQLNode{ID: "gid://gitlab/Group/" + id[1], FullPath: fullPath})
+				}
+			}
+			data["groups"] = map[string]any{"nodes": nodes}
+		}
+		for _, alias := range graphQLAliases.FindAllStringSubmatch(q.Query, -1) {
+			if node, ok := resources[alias[3]]; ok {
+				data[alias[1]] = node
+				continue
+			}
+			data[alias[1]] = nil
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := gitlab.NewClient("token", gitlab.WithBaseURL(srv.URL+"/api/v4"))
+	if err != nil {
+		t.Fatalf("cannot create gitlab client: %v", err)
+	}
+	return client, &queries
+}
+
+func TestProjectImporterGraphQL(t *testing.T) {
+	project := func(id int, fullPath string) graphQLNode {
+		return graphQLNode{
+			ID:       fmt.Sprintf("gid://gitlab/Project/%d", id),
+			Path:     fullPath[len(fullPath)-3:],
+			FullPath: fullPath,
+			WebURL:   "https://gitlab.example.com/" + fullPath,
+		}
+	}
+	client, queries := newGraphQLServer(t,
+		map[string]string{"1": "team", "2": "team/sub"},
+		map[string]graphQLNode{
+			"team/one":     project(11, "team/one"),
+			"team/two":     project(12, "team/two"),
+			"team/sub/one": project(21, "team/sub/one"),
+		},
+	)
+
+	desired := map[string]*resource.DesiredComposed{
+		"1/one": newDesiredProject(t, 1, "one"),
+		"1/two": newDesiredProject(t, 1, "two"),
+		"2/one": newDesiredProject(t, 2, "one"),
+		"2/six": newDesiredProject(t, 2, "six"),
+		"3/one": newDesiredProject(t, 3, "one"),
+	}
+
+	p := &ProjectImporter{}
+	if err := p.PassClient(client); err != nil {
+		t.Fatalf("p.PassClient(...): %v", err)
+	}
+	if err := p.PassInput(&v1beta1.Input{LookupBackend: v1beta1.LookupBackendGraphQL}); err != nil {
+		t.Fatalf("p.PassInput(...): %v", err)
+	}
+	for name, des := range desired {
+		if err := p.Plan(des); err != nil {
+			t.Fatalf("p.Plan(%s): %v", name, err)
+		}
+	}
+
+	got := map[string]importer.Result{}
+	for name, des := range desired {
+		result, err := p.Import(context.Background(), des)
+		if err != nil {
+			got[name] = importer.Result{ExternalName: "error"}
+			continue
+		}
+		got[name] = result
+	}
+
+	want := map[string]importer.Result{
+		"1/one": {
+			ExternalName: "11",
+			FullPath:     "team/one",
+			NamespaceID:  1,
+			WebURL:       "https://gitlab.example.com/team/one",
+			Strategy:     strategyGraphQL,
+			Candidates:   []importer.Candidate{{ID: "11", FullPath: "team/one"}},
+		},
+		"1/two": {
+			ExternalName: "12",
+			FullPath:     "team/two",
+			NamespaceID:  1,
+			WebURL:       "https://gitlab.example.com/team/two",
+			Strategy:     strategyGraphQL,
+			Candidates:   []importer.Candidate{{ID: "12", FullPath: "team/two"}},
+		},
+		"2/one": {
+			ExternalName: "21",
+			FullPath:     "team/sub/one",
+			NamespaceID:  2,
+			WebURL:       "https://gitlab.example.com/team/sub/one",
+			Strategy:     strategyGraphQL,
+			Candidates:   []importer.Candidate{{ID: "21", FullPath: "team/sub/one"}},
+		},
+		"2/six": {ExternalName: "error"},
+		"3/one": {ExternalName: "error"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("p.Import(...): -want external-names, +got external-names:\n%s", diff)
+	}
+	if diff := cmp.Diff(2, *queries); diff != "" {
+		t.Errorf("p.Import(...): -want queries, +got queries:\n%s", diff)
+	}
+}
@@ -1,10 +1,15 @@
 package gitlabimporter
 
 import (
+	"context"
 	"strconv"
+	"strings"
 
-	"github.com/simon-fredrich/function-gitlab-importer/internal"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
 	"github.com/simon-fredrich/function-gitlab-importer/internal/handler/gitlabhandler"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/metrics"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/tracing"
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 
 	"github.com/crossplane/function-sdk-go/errors"
@@ -20,43 +25,108 @@ import (
 // rather than creating new ones.
 type GroupImporter struct {
 	Client  *gitlab.Client
-	groupID *int
+	batch   batch[*gitlab.Group]
+	backend v1beta1.LookupBackend
+	// maxPages caps the pages fetched per namespace by the REST backend.
+	maxPages int
+}
+
+// Plan registers the desired group for a later Import. The subgroups of all
+// parent groups planned before an Import are listed together, each parent
+// group only once per request.
+func (g *GroupImporter) Plan(des *resource.DesiredComposed) error {
+	namespaceID, path, err := getGroupLocation(des)
+	if err != nil {
+		return errors.Errorf("cannot plan import: %w", err)
+	}
+	g.batch.plan(namespaceID, path)
+	return nil
 }
 
 // Import locates an existing GitLab group based on the desired resource specification
-// and sets its ID as the external-name in the Crossplane composition.
+// and returns its ID as the external-name. The desired resource is left
+// untouched, the caller sets the external-name once the import is allowed.
 //
 // It performs the following steps:
 //  1. Retrieves the parent group ID (namespaceID) and path from the desired resource.
-//  2. Uses the GitLab API client to find the subgroup within the parent group.
-//  3. Converts the group ID to a string and sets it as the external-name.
+//  2. Lists the subgroups of all planned parent groups that have not been listed yet.
+//  3. Finds the subgroup within the listing of its parent group.
+//  4. Converts the group ID to a string, which is the external-name.
+//
+// The result carries the full path and web URL of the group as returned by
+// the lookup, so no further requests are needed to describe it, as well as
+// the lookup strategy that found it and the candidates it has been chosen
+// from. The strategy and candidates are returned even if the group cannot be
+// found.
 //
 // Returns:
-//   - The external-name (group ID as a string) if successful.
+//   - The result holding the external-name (group ID as a string) if successful.
 //   - An error if the resource cannot be imported or the group cannot be found.
-func (g *GroupImporter) Import(des *resource.DesiredComposed) (string, error) {
-	handler := &gitlabhandler.GroupHandler{}
-	namespaceID, err := handler.GetNamespaceID(des)
+func (g *GroupImporter) Import(ctx context.Context, des *resource.DesiredComposed) (importer.Result, error) {
+	namespaceID, path, err := getGroupLocation(des)
 	if err != nil {
-		return "", errors.Errorf("cannot import resource: %w", err)
+		return importer.Result{}, errors.Errorf("cannot import resource: %w", err)
 	}
-	path, err := handler.GetPath(des)
-	if err != nil {
-		return "", errors.Errorf("cannot import resource: %w", err)
+	if g.batch.cached(namespaceID) {
+		metrics.CacheHit()
+	}
+	g.batch.plan(namespaceID, path)
+	g.batch.resolve(ctx, g.lookup)
+
+	// a listing stopped at the page limit may still contain the group
+	groups, listErr := g.batch.listing(namespaceID)
+	result := importer.Result{
+		NamespaceID: namespaceID,
+		Strategy:    lookupStrategy(g.backend, g.batch.resolvedPaths(namespaceID)),
+		Candidates:  groupCandidates(groups, path),
+	}
+	group, err := findGroup(groups, namespaceID, path)
+	if err != nil && listErr != nil {
+		return result, errors.Errorf("cannot import resource: cannot get subgroups: %w", listErr)
 	}
-	groupID, err := GetGroup(g.Client, namespaceID, path)
 	if err != nil {
-		return "", errors.Errorf("cannot import resource: %w", err)
+		return result, errors.Errorf("cannot import resource: %w", err)
 	}
-	g.groupID = &groupID
 
-	externalName := strconv.Itoa(groupID)
-	err = internal.SetExternalNameOnDesired(des, externalName)
+	result.ExternalName = strconv.Itoa(group.ID)
+	result.FullPath = group.FullPath
+	result.WebURL = group.WebURL
+	return result, nil
+}
+
+// Get returns the group with the ID externalName as returned by the GitLab
+// REST API. Its fields are named like in the API, e.g. "visibility". Custom
+// attributes are included if the token belongs to an administrator.
+func (g *GroupImporter) Get(ctx context.Context, externalName string) (map[string]any, error) {
+	id, err := strconv.Atoi(externalName)
 	if err != nil {
-		return "", errors.Errorf("cannot import resource: %w", err)
+		return nil, errors.Errorf("cannot parse group id %q: %w", externalName, err)
 	}
+	group, _, err := g.Client.Groups.GetGroup(id, &gitlab.GetGroupOptions{WithProjects: gitlab.Ptr(false), WithCustomAttributes: gitlab.Ptr(true)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Errorf("cannot get group %d: %w", id, err)
+	}
+	return toObject(group)
+}
 
-	return externalName, nil
+// Stamp writes the marker to the group with the ID externalName as custom
+// attribute. Groups do not have topics.
+func (g *GroupImporter) Stamp(ctx context.Context, externalName string, m importer.Marker) (bool, error) {
+	id, err := strconv.Atoi(externalName)
+	if err != nil {
+		return false, errors.Errorf("cannot parse group id %q: %w", externalName, err)
+	}
+	if m.Type != v1beta1.OwnershipMarkerCustomAttribute {
+		return false, errors.Errorf("cannot stamp %s marker on groups", m.Type)
+	}
+	return stampCustomAttribute(ctx, m,
+		func(key string, options ...gitlab.RequestOptionFunc) (*gitlab.CustomAttribute, *gitlab.Response, error) {
+			return g.Client.CustomAttribute.GetCustomGroupAttribute(id, key, options...)
+		},
+		func(c gitlab.CustomAttribute, options ...gitlab.RequestOptionFunc) (*gitlab.CustomAttribute, *gitlab.Response, error) {
+			return g.Client.CustomAttribute.SetCustomGroupAttribute(id, c, options...)
+		},
+	)
 }
 
 // PassClient assigns a GitLab client to the GroupImporter.
@@ -73,74 +143,90 @@ func (g *GroupImporter) PassClient(client any) error {
 	return nil
 }
 
-// GetContext returns the full path of the external group resource.
-//
-// It expects the following values to be available:
-//   - GroupImporter.groupID: Needs this value to search group on gitlab.
-//   - GroupImporter.Client: Needs the client to interact with the gitlab-API.
-func (g *GroupImporter) GetContext() (string, error) {
-	if g.groupID != nil {
-		if g.Client != nil {
-			group, rsp, err := g.Client.Groups.GetGroup(*g.groupID, &gitlab.GetGroupOptions{})
-			if err != nil {
-				return "", errors.Errorf("cannot get group, rsp: %v, err: %v", rsp, err)
-			}
-			return group.FullPath, nil
-		}
-		return "", errors.New("client has not been initialized jet, use importer.PassClient first")
+// PassInput configures the GroupImporter using the Function input. It selects
+// the backend used to look up existing groups.
+func (g *GroupImporter) PassInput(in *v1beta1.Input) error {
+	switch in.LookupBackend {
+	case "", v1beta1.LookupBackendREST, v1beta1.LookupBackendGraphQL:
+		g.backend = in.LookupBackend
+		g.maxPages = in.MaxListPages
+		return nil
+	default:
+		return errors.Errorf("unknown lookup backend %q", in.LookupBackend)
 	}
-	return "", errors.New("groupID has not been initialized jet, use importer.Import first")
 }
 
-// GetGroup returns the ID of a GitLab subgroup given its namespace ID and path.
-// It retrieves all subgroups under the specified parent group and searches for a match.
-//
-// Returns:
-//   - The subgroup ID if found.
-//   - An error if the subgroup cannot be found or the API call fails.
-func GetGroup(client *gitlab.Client, namespaceID int, path string) (int, error) {
-	// namespaceID is the ID of the parentgroup containing the desired subgroup
-	parentID := namespaceID
-
-	// find group based on path
-	groups, err := getSubGroups(client, parentID)
-	if err != nil {
-		return -1, errors.Errorf("cannot get subgroups: %w", err)
+// lookup returns the subgroups of the pending parent groups using the configured backend.
+func (g *GroupImporter) lookup(ctx context.Context, pending map[int][]string) (map[int][]*gitlab.Group, map[int]error) {
+	ctx, span := tracing.Start(ctx, "LookupGroups",
+		tracing.AttrLookupBackend.String(string(g.backend)),
+		tracing.AttrPending.Int(len(pending)),
+	)
+	defer span.End()
+
+	if g.backend == v1beta1.LookupBackendGraphQL {
+		return getGroupsGraphQL(ctx, g.Client, pending)
 	}
+	return perNamespace(func(ctx context.Context, namespaceID int, paths []string) ([]*gitlab.Group, error) {
+		return getSubGroups(ctx, g.Client, namespaceID, searchTerm(paths), g.maxPages)
+	})(ctx, pending)
+}
+
+// findGroup returns the group with the given path out of the
+// subgroups of the parent group with the ID parentID.
+func findGroup(groups []*gitlab.Group, parentID int, path string) (*gitlab.Group, error) {
 	for _, group := range groups {
 		if group.Path == path {
-			return group.ID, nil
+			return group, nil
 		}
 	}
-	return -1, errors.Errorf("there is no group with matching path in parent group with id: %+v", parentID)
+	return nil, errors.Errorf("there is no group with matching path in parent group with id: %+v", parentID)
 }
 
-// getSubGroups returns all groups of a given parent group.
-func getSubGroups(client *gitlab.Client, groupID int) ([]*gitlab.Group, error) {
-	subgroupsTotal := []*gitlab.Group{}
-	page := 1
-
-	// Iterate over all pages to retrieve all possible subgroups.
-	for {
-		opt := &gitlab.ListSubGroupsOptions{
-			AllAvailable: gitlab.Ptr(true),
-			ListOptions: gitlab.ListOptions{
-				PerPage: 10,
-				Page:    page,
-			},
+// groupCandidates returns the groups of a listing whose path contains path,
+// i.e. the groups a search for path matches.
+func groupCandidates(groups []*gitlab.Group, path string) []importer.Candidate {
+	candidates := []importer.Candidate{}
+	for _, group := range groups {
+		if strings.Contains(strings.ToLower(group.Path), strings.ToLower(path)) {
+			candidates = append(candidates, importer.Candidate{ID: strconv.Itoa(group.ID), FullPath: group.FullPath})
 		}
+	}
+	return candidates
+}
 
-		subgroups, resp, err := client.Groups.ListSubGroups(groupID, opt)
-		if err != nil {
-			return nil, errors.Errorf("cannot get list of subgroups: %w; gitlab resp: %+v", err, resp)
-		}
-		subgroupsTotal = append(subgroupsTotal, subgroups...)
+// getGroupLocation returns the parent group ID and the path of a desired group.
+func getGroupLocation(des *resource.DesiredComposed) (int, string, error) {
+	handler := &gitlabhandler.GroupHandler{}
+	namespaceID, err := handler.GetNamespaceID(des)
+	if err != nil {
+		return -1, "", err
+	}
+	path, err := handler.GetPath(des)
+	if err != nil {
+		return -1, "", err
+	}
+	return namespaceID, path, nil
+}
 
-		if resp.CurrentPage >= resp.TotalPages {
-			break
-		}
-		page++
+// getSubGroups returns all groups of a given parent group. If searchTerm is
+// not empty, only subgroups whose name or path contains it are returned.
+// If the parent group has more subgroups than maxPages pages hold, the
+// subgroups listed so far are returned together with ErrPageLimitReached.
+func getSubGroups(ctx context.Context, client *gitlab.Client, groupID int, searchTerm string, maxPages int) ([]*gitlab.Group, error) {
+	opt := gitlab.ListSubGroupsOptions{
+		AllAvailable: gitlab.Ptr(true),
+	}
+	if searchTerm != "" {
+		opt.Search = gitlab.Ptr(searchTerm)
 	}
 
-	return subgroupsTotal, nil
+	subgroups, err := listAllPages(ctx, maxPages, func(ctx context.Context, listOpt gitlab.ListOptions) ([]*gitlab.Group, *gitlab.Response, error) {
+		opt.ListOptions = listOpt
+		return client.Groups.ListSubGroups(groupID, &opt, gitlab.WithContext(ctx))
+	})
+	if err != nil {
+		return subgroups, errors.Errorf("cannot get list of subgroups: %w", err)
+	}
+	return subgroups, nil
 }
@@ -0,0 +1,119 @@
+package gitlabimporter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+// newDesiredGroup returns a desired group located in the given parent group.
+func newDesiredGroup(t *testing.T, parentID int, path string) *resource.DesiredComposed {
+	t.Helper()
+	comp := composed.New()
+	comp.SetUnstructuredContent(map[string]any{
+		"apiVersion": "groups.gitlab.crossplane.io/v1alpha1",
+		"kind":       "Group",
+		"spec": map[string]any{
+			"forProvider": map[string]any{
+				"parentId": int64(parentID),
+				"path":     path,
+			},
+		},
+	})
+	return &resource.DesiredComposed{Resource: comp}
+}
+
+// newGroupServer starts a namespaceServer serving the given subgroups per
+// parent group.
+func newGroupServer(t *testing.T, groups map[string][]*gitlab.Group) (*gitlab.Client, *namespaceServer) {
+	t.Helper()
+	return newNamespaceServer(t, "subgroups", groups, func(g *gitlab.Group) string { return g.Path })
+}
+
+func TestGroupImporterImport(t *testing.T) {
+	// groups returns n groups, the last one with the path "z". The path is
+	// too short to be searched for, so the whole parent group has to be listed.
+	groups := func(n int) []*gitlab.Group {
+		g := make([]*gitlab.Group, 0, n)
+		for i := 1; i < n; i++ {
+			g = append(g, &gitlab.Group{ID: i, Path: "group-" + strconv.Itoa(i)})
+		}
+		return append(g, &gitlab.Group{ID: n, Path: "z"})
+	}
+
+	type args struct {
+		groups       []*gitlab.Group
+		maxListPages int
+		path         string
+	}
+	type want struct {
+		externalName string
+		err          error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FoundOnLaterPage": {
+			reason: "Groups on later pages should be found by following the next page.",
+			args:   args{groups: groups(3*perPage + 1), path: "z"},
+			want:   want{externalName: strconv.Itoa(3*perPage + 1)},
+		},
+		"FoundBeforePageLimit": {
+			reason: "Groups listed before the page limit is reached should be found.",
+			args:   args{groups: groups(3*perPage + 1), maxListPages: 2, path: "group-1"},
+			want:   want{externalName: "1"},
+		},
+		"FoundBySearch": {
+			reason: "Groups should be searched for on the server to avoid listing the whole parent group.",
+			args:   args{groups: groups(3*perPage + 1), maxListPages: 2, path: "group-300"},
+			want:   want{externalName: "300"},
+		},
+		"PageLimitReached": {
+			reason: "Groups beyond the page limit should be reported as such instead of as missing.",
+			args:   args{groups: groups(3*perPage + 1), maxListPages: 2, path: "z"},
+			want:   want{err: ErrPageLimitReached},
+		},
+		"NotFound": {
+			reason: "A missing group should be reported as missing.",
+			args:   args{groups: groups(2), path: "missing"},
+			want:   want{err: cmpopts.AnyError},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, _ := newGroupServer(t, map[string][]*gitlab.Group{"1": tc.args.groups})
+
+			g := &GroupImporter{}
+			if err := g.PassClient(client); err != nil {
+				t.Fatalf("g.PassClient(...): %v", err)
+			}
+			if err := g.PassInput(&v1beta1.Input{MaxListPages: tc.args.maxListPages}); err != nil {
+				t.Fatalf("g.PassInput(...): %v", err)
+			}
+			des := newDesiredGroup(t, 1, tc.args.path)
+			if err := g.Plan(des); err != nil {
+				t.Fatalf("g.Plan(...): %v", err)
+			}
+			result, err := g.Import(context.Background(), des)
+
+			if diff := cmp.Diff(tc.want.externalName, result.ExternalName); diff != "" {
+				t.Errorf("%s\ng.Import(...): -want external-name, +got external-name:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\ng.Import(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
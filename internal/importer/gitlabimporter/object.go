@@ -0,0 +1,21 @@
+package gitlabimporter
+
+import (
+	"encoding/json"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// toObject converts a GitLab API type into a generic object. It is encoded to
+// JSON first, so its fields are named like in the GitLab API.
+func toObject(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Errorf("cannot encode object: %w", err)
+	}
+	obj := map[string]any{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, errors.Errorf("cannot decode object: %w", err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,60 @@
+package gitlabimporter
+
+import (
+	"context"
+
+	"github.com/simon-fredrich/function-gitlab-importer/internal/metrics"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/tracing"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+const (
+	// perPage is the maximum page size supported by the GitLab API.
+	perPage = 100
+
+	// defaultMaxPages caps the number of pages fetched per listing unless
+	// configured otherwise. Together with perPage it limits a single listing
+	// to 10,000 items.
+	defaultMaxPages = 100
+)
+
+// ErrPageLimitReached is returned when a listing has more pages than allowed.
+// The items fetched until then are returned together with this error.
+var ErrPageLimitReached = errors.New("page limit reached")
+
+// listAllPages fetches all pages of a listing by following the next-page
+// information of every response. Unlike the total page count, GitLab returns
+// it even for collections with more than 10,000 items. At most maxPages pages
+// are fetched, or defaultMaxPages if maxPages is not positive. Every page is
+// traced within its own span.
+func listAllPages[T any](ctx context.Context, maxPages int, list func(ctx context.Context, opt gitlab.ListOptions) ([]T, *gitlab.Response, error)) ([]T, error) {
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+	itemsTotal := []T{}
+	opt := gitlab.ListOptions{PerPage: perPage, Page: 1}
+
+	pages := 0
+	defer func() { metrics.PagesFetched(pages) }()
+
+	for {
+		pageCtx, span := tracing.Start(ctx, "ListPage", tracing.AttrPage.Int(opt.Page))
+		items, resp, err := list(pageCtx, opt)
+		tracing.End(span, err)
+		if err != nil {
+			return nil, errors.Errorf("%w; gitlab resp: %+v", err, resp)
+		}
+		pages++
+		itemsTotal = append(itemsTotal, items...)
+
+		if resp.NextPage == 0 {
+			return itemsTotal, nil
+		}
+		if pages >= maxPages {
+			return itemsTotal, errors.Errorf("stopped after %d pages of %d items, more items exist: %w", pages, perPage, ErrPageLimitReached)
+		}
+		opt.Page = resp.NextPage
+	}
+}
@@ -1,10 +1,16 @@
 package gitlabimporter
 
 import (
+	"context"
+	"slices"
 	"strconv"
+	"strings"
 
-	"github.com/simon-fredrich/function-gitlab-importer/internal"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
 	"github.com/simon-fredrich/function-gitlab-importer/internal/handler/gitlabhandler"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/metrics"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/tracing"
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 
 	"github.com/crossplane/function-sdk-go/errors"
@@ -19,44 +25,123 @@ import (
 // This type is intended for Crossplane functions that need to import existing GitLab projects
 // rather than creating new ones.
 type ProjectImporter struct {
-	Client    *gitlab.Client
-	projectID *int
+	Client  *gitlab.Client
+	batch   batch[*gitlab.Project]
+	backend v1beta1.LookupBackend
+	// maxPages caps the pages fetched per namespace by the REST backend.
+	maxPages int
+}
+
+// Plan registers the desired project for a later Import. The projects of all
+// namespaces planned before an Import are listed together, each namespace
+// only once per request.
+func (p *ProjectImporter) Plan(des *resource.DesiredComposed) error {
+	namespaceID, path, err := getProjectLocation(des)
+	if err != nil {
+		return errors.Errorf("cannot plan import: %w", err)
+	}
+	p.batch.plan(namespaceID, path)
+	return nil
 }
 
 // Import locates an existing GitLab project based on the desired resource specification
-// and sets its ID as the external-name in the Crossplane composition.
+// and returns its ID as the external-name. The desired resource is left
+// untouched, the caller sets the external-name once the import is allowed.
 //
 // It performs the following steps:
 //  1. Retrieves the namespace ID and path from the desired resource.
-//  2. Uses the GitLab API client to find the project within the namespace.
-//  3. Converts the project ID to a string and sets it as the external-name.
+//  2. Lists the projects of all planned namespaces that have not been listed yet.
+//  3. Finds the project within the listing of its namespace.
+//  4. Converts the project ID to a string, which is the external-name.
+//
+// The result carries the full path and web URL of the project as returned by
+// the lookup, so no further requests are needed to describe it, as well as
+// the lookup strategy that found it and the candidates it has been chosen
+// from. The strategy and candidates are returned even if the project cannot be
+// found.
 //
 // Returns:
-//   - The external-name (project ID as a string) if successful.
+//   - The result holding the external-name (project ID as a string) if successful.
 //   - An error if the resource cannot be imported or the project cannot be found.
-func (p *ProjectImporter) Import(des *resource.DesiredComposed) (string, error) {
-	handler := &gitlabhandler.ProjectHandler{}
-	namespaceID, err := handler.GetNamespaceID(des)
+func (p *ProjectImporter) Import(ctx context.Context, des *resource.DesiredComposed) (importer.Result, error) {
+	namespaceID, path, err := getProjectLocation(des)
 	if err != nil {
-		return "", errors.Errorf("cannot import resource: %w", err)
+		return importer.Result{}, errors.Errorf("cannot import resource: %w", err)
 	}
-	path, err := handler.GetPath(des)
-	if err != nil {
-		return "", errors.Errorf("cannot import resource: %w", err)
+	if p.batch.cached(namespaceID) {
+		metrics.CacheHit()
+	}
+	p.batch.plan(namespaceID, path)
+	p.batch.resolve(ctx, p.lookup)
+
+	// a listing stopped at the page limit may still contain the project
+	projects, listErr := p.batch.listing(namespaceID)
+	result := importer.Result{
+		NamespaceID: namespaceID,
+		Strategy:    lookupStrategy(p.backend, p.batch.resolvedPaths(namespaceID)),
+		Candidates:  projectCandidates(projects, path),
+	}
+	project, err := findProject(projects, namespaceID, path)
+	if err != nil && listErr != nil {
+		return result, errors.Errorf("cannot import resource: cannot get projects: %w", listErr)
 	}
-	projectID, err := GetProject(p.Client, namespaceID, path)
 	if err != nil {
-		return "", errors.Errorf("cannot import resource: %w", err)
+		return result, errors.Errorf("cannot import resource: %w", err)
 	}
-	p.projectID = &projectID
 
-	externalName := strconv.Itoa(projectID)
-	err = internal.SetExternalNameOnDesired(des, externalName)
+	result.ExternalName = strconv.Itoa(project.ID)
+	result.FullPath = project.PathWithNamespace
+	result.WebURL = project.WebURL
+	return result, nil
+}
+
+// Get returns the project with the ID externalName as returned by the GitLab
+// REST API. Its fields are named like in the API, e.g. "visibility". Custom
+// attributes are included if the token belongs to an administrator.
+func (p *ProjectImporter) Get(ctx context.Context, externalName string) (map[string]any, error) {
+	id, err := strconv.Atoi(externalName)
 	if err != nil {
-		return "", errors.Errorf("cannot import resource: %w", err)
+		return nil, errors.Errorf("cannot parse project id %q: %w", externalName, err)
 	}
+	project, _, err := p.Client.Projects.GetProject(id, &gitlab.GetProjectOptions{WithCustomAttributes: gitlab.Ptr(true)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Errorf("cannot get project %d: %w", id, err)
+	}
+	return toObject(project)
+}
 
-	return externalName, nil
+// Stamp writes the marker to the project with the ID externalName, either as
+// topic or as custom attribute. Existing topics are kept.
+func (p *ProjectImporter) Stamp(ctx context.Context, externalName string, m importer.Marker) (bool, error) {
+	id, err := strconv.Atoi(externalName)
+	if err != nil {
+		return false, errors.Errorf("cannot parse project id %q: %w", externalName, err)
+	}
+	switch m.Type {
+	case v1beta1.OwnershipMarkerTopic:
+		project, _, err := p.Client.Projects.GetProject(id, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+		if err != nil {
+			return false, errors.Errorf("cannot get project %d: %w", id, err)
+		}
+		if slices.Contains(project.Topics, m.String()) {
+			return false, nil
+		}
+		topics := append(slices.Clone(project.Topics), m.String())
+		if _, _, err := p.Client.Projects.EditProject(id, &gitlab.EditProjectOptions{Topics: &topics}, gitlab.WithContext(ctx)); err != nil {
+			return false, errors.Errorf("cannot add topic to project %d: %w", id, err)
+		}
+		return true, nil
+	case v1beta1.OwnershipMarkerCustomAttribute:
+		return stampCustomAttribute(ctx, m,
+			func(key string, options ...gitlab.RequestOptionFunc) (*gitlab.CustomAttribute, *gitlab.Response, error) {
+				return p.Client.CustomAttribute.GetCustomProjectAttribute(id, key, options...)
+			},
+			func(c gitlab.CustomAttribute, options ...gitlab.RequestOptionFunc) (*gitlab.CustomAttribute, *gitlab.Response, error) {
+				return p.Client.CustomAttribute.SetCustomProjectAttribute(id, c, options...)
+			},
+		)
+	}
+	return false, errors.Errorf("cannot stamp %s marker on projects", m.Type)
 }
 
 // PassClient assigns a GitLab client to the ProjectImporter.
@@ -73,75 +158,98 @@ func (p *ProjectImporter) PassClient(client any) error {
 	return nil
 }
 
-// GetContext returns the full path of the external project resource.
-//
-// It expects the following values to be available:
-//   - ProjectImporter.projectID: Needs this value to search project on gitlab.
-//   - ProjectImporter.Client: Needs the client to interact with the gitlab-API.
-func (p *ProjectImporter) GetContext() (string, error) {
-	if p.projectID != nil {
-		if p.Client != nil {
-			project, rsp, err := p.Client.Projects.GetProject(*p.projectID, &gitlab.GetProjectOptions{})
-			if err != nil {
-				return "", errors.Errorf("cannot get project, rsp: %v, err: %v", rsp, err)
-			}
-			return project.PathWithNamespace, nil
-		}
-		return "", errors.New("client has not been initialized jet, use importer.PassClient first")
+// PassInput configures the ProjectImporter using the Function input. It selects
+// the backend used to look up existing projects and how many pages of them are
+// listed per namespace.
+func (p *ProjectImporter) PassInput(in *v1beta1.Input) error {
+	switch in.LookupBackend {
+	case "", v1beta1.LookupBackendREST, v1beta1.LookupBackendGraphQL:
+		p.backend = in.LookupBackend
+		p.maxPages = in.MaxListPages
+		return nil
+	default:
+		return errors.Errorf("unknown lookup backend %q", in.LookupBackend)
 	}
-	return "", errors.New("projectID has not been initialized jet, use importer.Import first")
 }
 
-// GetProject returns the ID of a GitLab project given its namespace ID and path.
-// It retrieves all projects under the specified namespace and searches for a match.
-//
-// Returns:
-//   - The project ID if found.
-//   - An error if the project cannot be found or the API call fails.
-func GetProject(client *gitlab.Client, namespaceID int, path string) (int, error) {
-	// namespaceID is the ID of the parentgroup containing the desired project
-	parentID := namespaceID
-
-	// find project based on path
-	projects, err := getProjects(client, parentID, "")
-	if err != nil {
-		return -1, errors.Errorf("cannot get projects: %w", err)
+// lookup returns the projects of the pending namespaces using the configured backend.
+func (p *ProjectImporter) lookup(ctx context.Context, pending map[int][]string) (map[int][]*gitlab.Project, map[int]error) {
+	ctx, span := tracing.Start(ctx, "LookupProjects",
+		tracing.AttrLookupBackend.String(string(p.backend)),
+		tracing.AttrPending.Int(len(pending)),
+	)
+	defer span.End()
+
+	if p.backend == v1beta1.LookupBackendGraphQL {
+		return getProjectsGraphQL(ctx, p.Client, pending)
 	}
+	return perNamespace(func(ctx context.Context, namespaceID int, paths []string) ([]*gitlab.Project, error) {
+		return getProjects(ctx, p.Client, namespaceID, searchTerm(paths), p.maxPages)
+	})(ctx, pending)
+}
+
+// findProject returns the project with the given path out of the
+// projects of the namespace with the ID namespaceID.
+func findProject(projects []*gitlab.Project, namespaceID int, path string) (*gitlab.Project, error) {
 	for _, project := range projects {
 		if project.Path == path {
-			return project.ID, nil
+			return project, nil
 		}
 	}
-	return -1, errors.Errorf("there is no project with matching path in namespace with ID %+v", namespaceID)
+	return nil, errors.Errorf("there is no project with matching path in namespace with ID %+v", namespaceID)
 }
 
-// getProjects returns all projects of a given parent group.
-func getProjects(client *gitlab.Client, groupID int, searchTerm string) ([]*gitlab.Project, error) {
-	projectsTotal := []*gitlab.Project{}
-	page := 1
-
-	// Iterate over all pages to retrieve all possible projects in group with the given groupID.
-	for {
-		opt := &gitlab.ListGroupProjectsOptions{
-			Search: gitlab.Ptr(searchTerm),
-			ListOptions: gitlab.ListOptions{
-				PerPage: 10,
-				Page:    page,
-			},
-		}
-
-		projects, resp, err := client.Groups.ListGroupProjects(groupID, opt)
-		if err != nil {
-			return nil, errors.Errorf("cannot get list of projects: %w; gitlab resp: %+v", err, resp)
+// projectCandidates returns the projects of a listing whose path contains path,
+// i.e. the projects a search for path matches.
+func projectCandidates(projects []*gitlab.Project, path string) []importer.Candidate {
+	candidates := []importer.Candidate{}
+	for _, project := range projects {
+		if strings.Contains(strings.ToLower(project.Path), strings.ToLower(path)) {
+			candidates = append(candidates, importer.Candidate{ID: strconv.Itoa(project.ID), FullPath: project.PathWithNamespace})
 		}
+	}
+	return candidates
+}
 
-		projectsTotal = append(projectsTotal, projects...)
+// getProjectLocation returns the namespace ID and the path of a desired project.
+func getProjectLocation(des *resource.DesiredComposed) (int, string, error) {
+	handler := &gitlabhandler.ProjectHandler{}
+	namespaceID, err := handler.GetNamespaceID(des)
+	if err != nil {
+		return -1, "", err
+	}
+	path, err := handler.GetPath(des)
+	if err != nil {
+		return -1, "", err
+	}
+	return namespaceID, path, nil
+}
 
-		if resp.CurrentPage >= resp.TotalPages {
-			break
-		}
-		page++
+// getProjects returns all projects of a given parent group. If searchTerm is
+// not empty, only projects whose name or path contains it are returned.
+// Projects of subgroups and projects shared with the group are left out, as
+// they live in other namespaces. If the group has more projects than maxPages
+// pages hold, the projects listed so far are returned together with
+// ErrPageLimitReached.
+//
+// The listing is not restricted to owned projects, as the token used usually
+// belongs to a bot that does not own the projects it may import.
+func getProjects(ctx context.Context, client *gitlab.Client, groupID int, searchTerm string, maxPages int) ([]*gitlab.Project, error) {
+	opt := gitlab.ListGroupProjectsOptions{
+		Simple:           gitlab.Ptr(true),
+		WithShared:       gitlab.Ptr(false),
+		IncludeSubGroups: gitlab.Ptr(false),
+	}
+	if searchTerm != "" {
+		opt.Search = gitlab.Ptr(searchTerm)
 	}
 
-	return projectsTotal, nil
+	projects, err := listAllPages(ctx, maxPages, func(ctx context.Context, listOpt gitlab.ListOptions) ([]*gitlab.Project, *gitlab.Response, error) {
+		opt.ListOptions = listOpt
+		return client.Groups.ListGroupProjects(groupID, &opt, gitlab.WithContext(ctx))
+	})
+	if err != nil {
+		return projects, errors.Errorf("cannot get list of projects: %w", err)
+	}
+	return projects, nil
 }
@@ -0,0 +1,295 @@
+package gitlabimporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+// newDesiredProject returns a desired project located in the given namespace.
+func newDesiredProject(t *testing.T, namespaceID int, path string) *resource.DesiredComposed {
+	t.Helper()
+	comp := composed.New()
+	comp.SetUnstructuredContent(map[string]any{
+		"apiVersion": "projects.gitlab.crossplane.io/v1alpha1",
+		"kind":       "Project",
+		"spec": map[string]any{
+			"forProvider": map[string]any{
+				"namespaceId": int64(namespaceID),
+				"path":        path,
+			},
+		},
+	})
+	return &resource.DesiredComposed{Resource: comp}
+}
+
+// namespaceServer is a stand-in GitLab API serving a collection of the
+// namespaces, i.e. their projects or subgroups. It records the listings and
+// search terms requested per namespace.
+type namespaceServer struct {
+	mu       sync.Mutex
+	listings map[string]int
+	searches map[string][]string
+}
+
+// newNamespaceServer starts a namespaceServer serving the given items per
+// namespace as the collection, e.g. projects, and returns a client connected
+// to it. Items are searched for by the path returned by path.
+func newNamespaceServer[T any](t *testing.T, collection string, items map[string][]T, path func(T) string) (*gitlab.Client, *namespaceServer) {
+	t.Helper()
+	ns := &namespaceServer{listings: map[string]int{}, searches: map[string][]string{}}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// expect /api/v4/groups/<id>/<collection>
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/"), "/")
+		if len(parts) != 3 || parts[0] != "groups" || parts[2] != collection {
+			http.NotFound(w, r)
+			return
+		}
+		search := r.URL.Query().Get("search")
+		ns.mu.Lock()
+		ns.listings[parts[1]]++
+		ns.searches[parts[1]] = append(ns.searches[parts[1]], search)
+		ns.mu.Unlock()
+
+		found := []T{}
+		for _, item := range items[parts[1]] {
+			if strings.Contains(path(item), search) {
+				found = append(found, item)
+			}
+		}
+
+		// serve the requested page and link the next one, like GitLab does
+		// for collections too large to be counted
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		start := min((page-1)*perPage, len(found))
+		end := min(start+perPage, len(found))
+		if end < len(found) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(found[start:end])
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := gitlab.NewClient("token", gitlab.WithBaseURL(srv.URL+"/api/v4"))
+	if err != nil {
+		t.Fatalf("cannot create gitlab client: %v", err)
+	}
+	return client, ns
+}
+
+// newProjectServer starts a namespaceServer serving the given projects per
+// namespace.
+func newProjectServer(t *testing.T, projects map[string][]*gitlab.Project) (*gitlab.Client, *namespaceServer) {
+	t.Helper()
+	return newNamespaceServer(t, "projects", projects, func(p *gitlab.Project) string { return p.Path })
+}
+
+func TestProjectImporterListsEachNamespaceOnce(t *testing.T) {
+	client, srv := newProjectServer(t, map[string][]*gitlab.Project{
+		"1": {{ID: 11, Path: "one"}, {ID: 12, Path: "two"}, {ID: 13, Path: "three"}},
+		"2": {{ID: 21, Path: "one"}, {ID: 22, Path: "one-more"}},
+	})
+
+	desired := map[string]*resource.DesiredComposed{
+		"1/one":   newDesiredProject(t, 1, "one"),
+		"1/two":   newDesiredProject(t, 1, "two"),
+		"1/three": newDesiredProject(t, 1, "three"),
+		"2/one":   newDesiredProject(t, 2, "one"),
+	}
+
+	p := &ProjectImporter{}
+	if err := p.PassClient(client); err != nil {
+		t.Fatalf("p.PassClient(...): %v", err)
+	}
+	for name, des := range desired {
+		if err := p.Plan(des); err != nil {
+			t.Fatalf("p.Plan(%s): %v", name, err)
+		}
+	}
+
+	got := map[string]string{}
+	for name, des := range desired {
+		result, err := p.Import(context.Background(), des)
+		if err != nil {
+			t.Fatalf("p.Import(%s): %v", name, err)
+		}
+		got[name] = result.ExternalName
+		// the external-name is only set once the import passed all guards
+		if externalName := des.Resource.GetAnnotations()["crossplane.io/external-name"]; externalName != "" {
+			t.Errorf("p.Import(%s): desired resource has been given external-name %s", name, externalName)
+		}
+	}
+
+	want := map[string]string{"1/one": "11", "1/two": "12", "1/three": "13", "2/one": "21"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("p.Import(...): -want external-names, +got external-names:\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]int{"1": 1, "2": 1}, srv.listings); diff != "" {
+		t.Errorf("p.Import(...): -want listings per namespace, +got listings per namespace:\n%s", diff)
+	}
+	// only namespaces with a single pending path can be searched on the server
+	if diff := cmp.Diff(map[string][]string{"1": {""}, "2": {"one"}}, srv.searches); diff != "" {
+		t.Errorf("p.Import(...): -want searches per namespace, +got searches per namespace:\n%s", diff)
+	}
+}
+
+func TestProjectImporterImport(t *testing.T) {
+	// projects returns n projects, the last one with the path "z". The path is
+	// too short to be searched for, so the whole namespace has to be listed.
+	projects := func(n int) []*gitlab.Project {
+		p := make([]*gitlab.Project, 0, n)
+		for i := 1; i < n; i++ {
+			p = append(p, &gitlab.Project{ID: i, Path: "project-" + strconv.Itoa(i)})
+		}
+		return append(p, &gitlab.Project{ID: n, Path: "z"})
+	}
+
+	type args struct {
+		projects     []*gitlab.Project
+		maxListPages int
+		path         string
+	}
+	type want struct {
+		externalName string
+		err          error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FoundOnLaterPage": {
+			reason: "Projects on later pages should be found by following the next page.",
+			args:   args{projects: projects(3*perPage + 1), path: "z"},
+			want:   want{externalName: strconv.Itoa(3*perPage + 1)},
+		},
+		"FoundBeforePageLimit": {
+			reason: "Projects listed before the page limit is reached should be found.",
+			args:   args{projects: projects(3*perPage + 1), maxListPages: 2, path: "project-1"},
+			want:   want{externalName: "1"},
+		},
+		"FoundBySearch": {
+			reason: "Projects should be searched for on the server to avoid listing the whole namespace.",
+			args:   args{projects: projects(3*perPage + 1), maxListPages: 2, path: "project-300"},
+			want:   want{externalName: "300"},
+		},
+		"PageLimitReached": {
+			reason: "Projects beyond the page limit should be reported as such instead of as missing.",
+			args:   args{projects: projects(3*perPage + 1), maxListPages: 2, path: "z"},
+			want:   want{err: ErrPageLimitReached},
+		},
+		"NotFound": {
+			reason: "A missing project should be reported as missing.",
+			args:   args{projects: projects(2), path: "missing"},
+			want:   want{err: cmpopts.AnyError},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, _ := newProjectServer(t, map[string][]*gitlab.Project{"1": tc.args.projects})
+
+			p := &ProjectImporter{}
+			if err := p.PassClient(client); err != nil {
+				t.Fatalf("p.PassClient(...): %v", err)
+			}
+			if err := p.PassInput(&v1beta1.Input{MaxListPages: tc.args.maxListPages}); err != nil {
+				t.Fatalf("p.PassInput(...): %v", err)
+			}
+			des := newDesiredProject(t, 1, tc.args.path)
+			if err := p.Plan(des); err != nil {
+				t.Fatalf("p.Plan(...): %v", err)
+			}
+			result, err := p.Import(context.Background(), des)
+
+			if diff := cmp.Diff(tc.want.externalName, result.ExternalName); diff != "" {
+				t.Errorf("%s\np.Import(...): -want external-name, +got external-name:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\np.Import(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestProjectImporterPageLimit(t *testing.T) {
+	// three pages of projects, listed as a whole as several paths are pending
+	projects := make([]*gitlab.Project, 0, 3*perPage)
+	for i := 1; i <= 3*perPage; i++ {
+		projects = append(projects, &gitlab.Project{ID: i, Path: "project-" + strconv.Itoa(i)})
+	}
+	paths := []string{"project-1", "project-150", "project-250", "project-300"}
+
+	cases := map[string]struct {
+		reason       string
+		maxListPages int
+		want         map[string]string
+	}{
+		"PageLimitReached": {
+			reason:       "Pending paths beyond the page limit should be reported as such, the others should be found.",
+			maxListPages: 2,
+			want:         map[string]string{"project-1": "1", "project-150": "150", "project-250": "error", "project-300": "error"},
+		},
+		"RaisedPageLimit": {
+			reason:       "All pending paths should be found once the page limit covers the whole namespace.",
+			maxListPages: 3,
+			want:         map[string]string{"project-1": "1", "project-150": "150", "project-250": "250", "project-300": "300"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, _ := newProjectServer(t, map[string][]*gitlab.Project{"1": projects})
+
+			p := &ProjectImporter{}
+			if err := p.PassClient(client); err != nil {
+				t.Fatalf("p.PassClient(...): %v", err)
+			}
+			if err := p.PassInput(&v1beta1.Input{MaxListPages: tc.maxListPages}); err != nil {
+				t.Fatalf("p.PassInput(...): %v", err)
+			}
+			desired := map[string]*resource.DesiredComposed{}
+			for _, path := range paths {
+				desired[path] = newDesiredProject(t, 1, path)
+				if err := p.Plan(desired[path]); err != nil {
+					t.Fatalf("p.Plan(%s): %v", path, err)
+				}
+			}
+
+			got := map[string]string{}
+			for path, des := range desired {
+				result, err := p.Import(context.Background(), des)
+				switch {
+				case errors.Is(err, ErrPageLimitReached):
+					got[path] = "error"
+				case err != nil:
+					t.Fatalf("%s\np.Import(%s): %v", tc.reason, path, err)
+				default:
+					got[path] = result.ExternalName
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\np.Import(...): -want external-names, +got external-names:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package gitlabimporter
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// stampCustomAttribute sets the custom attribute m.Key to m.Value unless it
+// has this value already. Custom attributes can only be read and written by
+// administrators. The client puts keys into the path as they are, so get and
+// set are passed the escaped key, as keys like crossplane.io/owner contain
+// slashes.
+func stampCustomAttribute(
+	ctx context.Context,
+	m importer.Marker,
+	get func(key string, options ...gitlab.RequestOptionFunc) (*gitlab.CustomAttribute, *gitlab.Response, error),
+	set func(c gitlab.CustomAttribute, options ...gitlab.RequestOptionFunc) (*gitlab.CustomAttribute, *gitlab.Response, error),
+) (bool, error) {
+	key := url.PathEscape(m.Key)
+	current, _, err := get(key, gitlab.WithContext(ctx))
+	switch {
+	case errors.Is(err, gitlab.ErrNotFound):
+	case err != nil:
+		return false, errors.Errorf("cannot get custom attribute %s: %w", m.Key, err)
+	case current.Value == m.Value:
+		return false, nil
+	}
+	if _, _, err := set(gitlab.CustomAttribute{Key: key, Value: m.Value}, gitlab.WithContext(ctx)); err != nil {
+		return false, errors.Errorf("cannot set custom attribute %s: %w", m.Key, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,126 @@
+package gitlabimporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestProjectImporterStamp(t *testing.T) {
+	topic := importer.Marker{Type: v1beta1.OwnershipMarkerTopic, Key: "crossplane.io/owner", Value: "uid"}
+	attribute := importer.Marker{Type: v1beta1.OwnershipMarkerCustomAttribute, Key: "crossplane.io/owner", Value: "uid"}
+
+	type args struct {
+		topics     []string
+		attributes map[string]string
+		marker     importer.Marker
+	}
+	type want struct {
+		changed    bool
+		topics     []string
+		attributes map[string]string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"AddTopic": {
+			reason: "The marker should be added to the existing topics.",
+			args:   args{topics: []string{"backend"}, marker: topic},
+			want:   want{changed: true, topics: []string{"backend", "crossplane.io/owner=uid"}},
+		},
+		"TopicPresent": {
+			reason: "The project should not be edited if it has the topic already.",
+			args:   args{topics: []string{"crossplane.io/owner=uid"}, marker: topic},
+			want:   want{topics: []string{"crossplane.io/owner=uid"}},
+		},
+		"SetCustomAttribute": {
+			reason: "A missing custom attribute should be set.",
+			args:   args{marker: attribute},
+			want:   want{changed: true, attributes: map[string]string{"crossplane.io/owner": "uid"}},
+		},
+		"OverwriteCustomAttribute": {
+			reason: "A custom attribute naming another owner should be overwritten.",
+			args:   args{attributes: map[string]string{"crossplane.io/owner": "other"}, marker: attribute},
+			want:   want{changed: true, attributes: map[string]string{"crossplane.io/owner": "uid"}},
+		},
+		"CustomAttributePresent": {
+			reason: "A custom attribute naming the owner should not be set again.",
+			args:   args{attributes: map[string]string{"crossplane.io/owner": "uid"}, marker: attribute},
+			want:   want{attributes: map[string]string{"crossplane.io/owner": "uid"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			topics := tc.args.topics
+			attributes := tc.args.attributes
+
+			// serve project 42 with its topics and custom attributes
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.URL.Path == "/api/v4/projects/42" && r.Method == http.MethodGet:
+					_ = json.NewEncoder(w).Encode(gitlab.Project{ID: 42, Topics: topics})
+				case r.URL.Path == "/api/v4/projects/42" && r.Method == http.MethodPut:
+					opt := gitlab.EditProjectOptions{}
+					_ = json.NewDecoder(r.Body).Decode(&opt)
+					topics = *opt.Topics
+					_ = json.NewEncoder(w).Encode(gitlab.Project{ID: 42, Topics: topics})
+				case strings.HasPrefix(r.URL.Path, "/api/v4/projects/42/custom_attributes/"):
+					key := strings.TrimPrefix(r.URL.Path, "/api/v4/projects/42/custom_attributes/")
+					if r.Method == http.MethodPut {
+						c := gitlab.CustomAttribute{}
+						_ = json.NewDecoder(r.Body).Decode(&c)
+						if attributes == nil {
+							attributes = map[string]string{}
+						}
+						attributes[key] = c.Value
+					}
+					value, ok := attributes[key]
+					if !ok {
+						http.NotFound(w, r)
+						return
+					}
+					_ = json.NewEncoder(w).Encode(gitlab.CustomAttribute{Key: key, Value: value})
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			t.Cleanup(srv.Close)
+			client, err := gitlab.NewClient("token", gitlab.WithBaseURL(srv.URL+"/api/v4"))
+			if err != nil {
+				t.Fatalf("cannot create gitlab client: %v", err)
+			}
+
+			p := &ProjectImporter{}
+			if err := p.PassClient(client); err != nil {
+				t.Fatalf("p.PassClient(...): %v", err)
+			}
+			changed, err := p.Stamp(context.Background(), "42", tc.args.marker)
+			if err != nil {
+				t.Fatalf("%s\np.Stamp(...): %v", tc.reason, err)
+			}
+
+			if diff := cmp.Diff(tc.want.changed, changed); diff != "" {
+				t.Errorf("%s\np.Stamp(...): -want changed, +got changed:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.topics, topics); diff != "" {
+				t.Errorf("%s\np.Stamp(...): -want topics, +got topics:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.attributes, attributes); diff != "" {
+				t.Errorf("%s\np.Stamp(...): -want custom attributes, +got custom attributes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package importer
 
 import (
+	"context"
+
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+
 	"github.com/crossplane/function-sdk-go/resource"
 )
 
@@ -9,13 +13,74 @@ import (
 // while maintaining a consistent method signature.
 //
 // Method:
+//   - Plan: Registers a desired resource that is going to be imported within the
+//     current request. Implementations can use the collected resources to batch
+//     their lookups, e.g. to query every namespace only once.
 //   - Import: Takes a desired resource and performs the import operation,
-//     returning a string identifier (such as an external name) or an error.
+//     returning a Result holding the identifier (such as an external name)
+//     of the external resource or an error. The context is passed on to all
+//     requests sent to look up the external resource. The desired resource
+//     must not be modified, as the import may still be refused afterwards.
 //   - PassClient: Provides the underlying provider client to the importer.
 //     The client must be of the expected type (e.g., *gitlab.Client), otherwise
 //     an error is returned.
+//   - Get: Returns the external resource with the given external-name as
+//     returned by the provider's API, decoded into a generic object. It is
+//     only called if the object is needed, e.g. to evaluate import conditions.
+//   - Stamp: Writes a marker to the external resource with the given
+//     external-name unless it carries the marker already. It returns true if
+//     the external resource has been changed.
+//   - PassInput: Provides the Function input to the importer, so it can pick up
+//     importer related settings such as the lookup backend.
 type Importer interface {
-	Import(des *resource.DesiredComposed) (string, error)
+	Plan(des *resource.DesiredComposed) error
+	Import(ctx context.Context, des *resource.DesiredComposed) (Result, error)
+	Get(ctx context.Context, externalName string) (map[string]any, error)
+	Stamp(ctx context.Context, externalName string, m Marker) (bool, error)
 	PassClient(client any) error
-	GetContext() (string, error)
+	PassInput(in *v1beta1.Input) error
+}
+
+// Result describes the external resource found by an Importer. Besides the
+// external-name it carries the details returned by the lookup itself, so
+// describing an imported resource does not need further requests.
+type Result struct {
+	// ExternalName is the identifier to be set as external-name.
+	ExternalName string
+	// FullPath is the human readable location of the external resource.
+	FullPath string
+	// NamespaceID is the ID of the namespace holding the external resource.
+	NamespaceID int
+	// WebURL links to the external resource. It is empty if the lookup did
+	// not return it.
+	WebURL string
+	// Strategy names the lookup strategy that found the external resource.
+	Strategy string
+	// Candidates are the external resources the lookup has considered. They
+	// are returned even if the external resource could not be found.
+	Candidates []Candidate
+}
+
+// Candidate is an external resource considered by a lookup.
+type Candidate struct {
+	// ID of the external resource.
+	ID string
+	// FullPath is the human readable location of the external resource.
+	FullPath string
+}
+
+// Marker is written to external resources to record who owns them.
+type Marker struct {
+	// Type of the marker, e.g. Topic.
+	Type v1beta1.OwnershipMarkerType
+	// Key of the marker, e.g. crossplane.io/owner.
+	Key string
+	// Value of the marker, e.g. the UID of the owning composite resource.
+	Value string
+}
+
+// String returns the marker as <key>=<value>, as used in topics and
+// descriptions.
+func (m Marker) String() string {
+	return m.Key + "=" + m.Value
 }
@@ -2,10 +2,12 @@ package internal
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 
 	"github.com/crossplane/crossplane-runtime/v2/apis/common"
 	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/policy"
 
 	"github.com/crossplane/function-sdk-go/errors"
 	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
@@ -122,24 +124,85 @@ func GetBoolAnnotation(obs resource.ObservedComposed, key string) (bool, error)
 }
 
 // SetManagedValues edits the desired composite resource to display that it
-// has been imported and therefore is being managed and sets managementPolicies.
-// If managementPolicies are provided within the input use them, otherwise default
-// to observe-only.
-func SetManagedValues(des *resource.DesiredComposed, in *v1beta1.Input) error {
+// has been imported and therefore is being managed and sets managementPolicies
+// as returned by ManagementPolicies. The deletion policy is set if the
+// management sets one.
+func SetManagedValues(des *resource.DesiredComposed, strategy v1beta1.ManagementPoliciesStrategy, m policy.Management) error {
 	// Mark resource to have its external-name managed.
 	SetBoolAnnotation(des, "crossplane.io/managed-external-name", true)
 
 	// Configure managementPolicies
-	managementPolicies := common.ManagementPolicies{}
-	if len(in.ManagementPolicies) > 0 {
-		managementPolicies = append(managementPolicies, in.ManagementPolicies...)
-	} else {
-		managementPolicies = append(managementPolicies, common.ManagementActionObserve)
-	}
-	err := des.Resource.SetValue("spec.managementPolicies", managementPolicies)
+	err := des.Resource.SetValue("spec.managementPolicies", ManagementPolicies(des, strategy, m.Policies))
 	if err != nil {
 		return errors.Errorf("cannot set managed values on resource: %w", err)
 	}
+	if m.DeletionPolicy != "" {
+		if err := des.Resource.SetValue("spec.deletionPolicy", m.DeletionPolicy); err != nil {
+			return errors.Errorf("cannot set managed values on resource: %w", err)
+		}
+	}
 
 	return nil
 }
+
+// ManagementPolicies returns the management policies of an imported resource
+// managed with the given policies. Policies already set on the desired
+// resource, e.g. by an earlier pipeline step, are merged according to the
+// strategy:
+//   - Override: the given policies replace them.
+//   - KeepExisting: they are kept.
+//   - Intersect: only those allowed by the given policies as well are kept.
+//     If none are left, the resource is observed only.
+func ManagementPolicies(des *resource.DesiredComposed, strategy v1beta1.ManagementPoliciesStrategy, policies common.ManagementPolicies) common.ManagementPolicies {
+	policies = append(common.ManagementPolicies{}, policies...)
+
+	// resources without management policies leave them empty
+	existing, _ := des.Resource.GetStringArray("spec.managementPolicies")
+	if len(existing) == 0 {
+		return policies
+	}
+	switch strategy {
+	case v1beta1.ManagementPoliciesStrategyKeepExisting:
+		return toManagementPolicies(existing)
+	case v1beta1.ManagementPoliciesStrategyIntersect:
+		return intersect(toManagementPolicies(existing), policies)
+	default:
+		return policies
+	}
+}
+
+// intersect returns the policies allowed by both a and b. All (*) allows
+// every policy.
+func intersect(a, b common.ManagementPolicies) common.ManagementPolicies {
+	switch {
+	case slices.Contains(a, common.ManagementActionAll):
+		return b
+	case slices.Contains(b, common.ManagementActionAll):
+		return a
+	}
+	policies := common.ManagementPolicies{}
+	for _, p := range a {
+		if slices.Contains(b, p) {
+			policies = append(policies, p)
+		}
+	}
+	if len(policies) == 0 {
+		return common.ManagementPolicies{common.ManagementActionObserve}
+	}
+	return policies
+}
+
+// toManagementPolicies converts strings to management policies.
+func toManagementPolicies(s []string) common.ManagementPolicies {
+	policies := make(common.ManagementPolicies, 0, len(s))
+	for _, p := range s {
+		policies = append(policies, common.ManagementAction(p))
+	}
+	return policies
+}
+
+// AllowsUpdates returns true if the management policies allow Crossplane to
+// update external resources.
+func AllowsUpdates(policies common.ManagementPolicies) bool {
+	return slices.Contains(policies, common.ManagementActionUpdate) || slices.Contains(policies, common.ManagementActionAll)
+}
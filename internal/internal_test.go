@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/apis/common"
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/policy"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestManagementPolicies(t *testing.T) {
+	type args struct {
+		in       *v1beta1.Input
+		existing []any
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   common.ManagementPolicies
+	}{
+		"DefaultToObserve": {
+			reason: "Without any policies the resource should be observed only.",
+			args:   args{in: &v1beta1.Input{}},
+			want:   common.ManagementPolicies{common.ManagementActionObserve},
+		},
+		"Override": {
+			reason: "The policies of the input should replace existing ones by default.",
+			args: args{
+				in:       &v1beta1.Input{ManagementPolicies: common.ManagementPolicies{common.ManagementActionObserve, common.ManagementActionUpdate}},
+				existing: []any{"*"},
+			},
+			want: common.ManagementPolicies{common.ManagementActionObserve, common.ManagementActionUpdate},
+		},
+		"KeepExisting": {
+			reason: "Existing policies should be kept.",
+			args: args{
+				in:       &v1beta1.Input{ManagementPoliciesStrategy: v1beta1.ManagementPoliciesStrategyKeepExisting},
+				existing: []any{"Observe", "Delete"},
+			},
+			want: common.ManagementPolicies{common.ManagementActionObserve, common.ManagementActionDelete},
+		},
+		"KeepExistingWithoutExisting": {
+			reason: "The policies of the input should be set if there are no existing ones.",
+			args: args{
+				in: &v1beta1.Input{
+					ManagementPoliciesStrategy: v1beta1.ManagementPoliciesStrategyKeepExisting,
+					ManagementPolicies:         common.ManagementPolicies{common.ManagementActionAll},
+				},
+			},
+			want: common.ManagementPolicies{common.ManagementActionAll},
+		},
+		"Intersect": {
+			reason: "Only existing policies allowed by the input should be kept.",
+			args: args{
+				in: &v1beta1.Input{
+					ManagementPoliciesStrategy: v1beta1.ManagementPoliciesStrategyIntersect,
+					ManagementPolicies:         common.ManagementPolicies{common.ManagementActionObserve, common.ManagementActionUpdate},
+				},
+				existing: []any{"Observe", "Update", "Delete"},
+			},
+			want: common.ManagementPolicies{common.ManagementActionObserve, common.ManagementActionUpdate},
+		},
+		"IntersectAll": {
+			reason: "All should allow every policy of the other side.",
+			args: args{
+				in: &v1beta1.Input{
+					ManagementPoliciesStrategy: v1beta1.ManagementPoliciesStrategyIntersect,
+					ManagementPolicies:         common.ManagementPolicies{common.ManagementActionAll},
+				},
+				existing: []any{"Observe", "LateInitialize"},
+			},
+			want: common.ManagementPolicies{common.ManagementActionObserve, common.ManagementActionLateInitialize},
+		},
+		"IntersectNothing": {
+			reason: "The resource should be observed only if no policy is left.",
+			args: args{
+				in: &v1beta1.Input{
+					ManagementPoliciesStrategy: v1beta1.ManagementPoliciesStrategyIntersect,
+					ManagementPolicies:         common.ManagementPolicies{common.ManagementActionUpdate},
+				},
+				existing: []any{"Delete"},
+			},
+			want: common.ManagementPolicies{common.ManagementActionObserve},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			des := &resource.DesiredComposed{Resource: composed.New()}
+			if tc.args.existing != nil {
+				if err := des.Resource.SetValue("spec.managementPolicies", tc.args.existing); err != nil {
+					t.Fatalf("cannot set existing management policies: %v", err)
+				}
+			}
+			got := ManagementPolicies(des, tc.args.in.ManagementPoliciesStrategy, policy.ManagementOf(tc.args.in, schema.GroupVersionKind{}, "project").Policies)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nManagementPolicies(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
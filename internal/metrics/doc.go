@@ -0,0 +1,7 @@
+// Package metrics records Prometheus metrics about the import activity of the
+// Function and the GitLab API requests it sends.
+//
+// Metrics are always recorded, but only exposed if the Function has been
+// started with a metrics address. All metrics are registered with Registry
+// rather than the global Prometheus registry.
+package metrics
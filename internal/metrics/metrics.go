@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+const namespace = "function_gitlab_importer"
+
+// Registry holds all metrics of the Function.
+var Registry = prometheus.NewRegistry()
+
+var (
+	importsAttempted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "imports_attempted_total",
+		Help:      "Number of composed resources the Function tried to import.",
+	}, []string{"group", "version", "kind"})
+
+	importsSucceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "imports_succeeded_total",
+		Help:      "Number of composed resources the Function imported.",
+	}, []string{"group", "version", "kind"})
+
+	importsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "imports_failed_total",
+		Help:      "Number of composed resources the Function failed to import.",
+	}, []string{"group", "version", "kind"})
+
+	gitlabRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "gitlab_request_duration_seconds",
+		Help:      "Latency of GitLab API requests by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	lookupPages = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lookup_pages",
+		Help:      "Number of pages fetched per GitLab listing.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 50, 100},
+	})
+
+	lookupCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lookup_cache_hits_total",
+		Help:      "Number of imports served from a listing fetched earlier within the same request.",
+	})
+
+	runFunctionDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "run_function_duration_seconds",
+		Help:      "Duration of RunFunction calls.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		importsAttempted,
+		importsSucceeded,
+		importsFailed,
+		gitlabRequestDuration,
+		lookupPages,
+		lookupCacheHits,
+		runFunctionDuration,
+	)
+}
+
+// ImportAttempted records an attempt to import a resource of the given GVK.
+func ImportAttempted(gvk schema.GroupVersionKind) {
+	importsAttempted.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Inc()
+}
+
+// ImportSucceeded records a resource of the given GVK that has been imported.
+func ImportSucceeded(gvk schema.GroupVersionKind) {
+	importsSucceeded.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Inc()
+}
+
+// ImportFailed records a resource of the given GVK that could not be imported.
+func ImportFailed(gvk schema.GroupVersionKind) {
+	importsFailed.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Inc()
+}
+
+// PagesFetched records the number of pages fetched by a single listing.
+func PagesFetched(pages int) {
+	lookupPages.Observe(float64(pages))
+}
+
+// CacheHit records an import served from a listing fetched earlier.
+func CacheHit() {
+	lookupCacheHits.Inc()
+}
+
+// RunFunctionDone records the duration of a RunFunction call started at start.
+func RunFunctionDone(start time.Time) {
+	runFunctionDuration.Observe(time.Since(start).Seconds())
+}
+
+// InstrumentTransport returns a RoundTripper that records the latency and
+// status code of every request sent through next.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return promhttp.InstrumentRoundTripperDuration(gitlabRequestDuration, next)
+}
+
+// Serve exposes the metrics of Registry on /metrics at the given address. It
+// returns an error if it cannot listen on the address. Otherwise metrics are
+// served in the background.
+func Serve(address string) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Errorf("cannot listen for metrics on %s: %w", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(lis) //nolint:errcheck // the server runs as long as the Function
+	return nil
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestMetrics(t *testing.T) {
+	// gather the metrics of the Function without the Go and process
+	// collectors, whose values cannot be predicted
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(importsAttempted, importsSucceeded, importsFailed, gitlabRequestDuration, lookupPages, lookupCacheHits, runFunctionDuration)
+
+	project := schema.GroupVersionKind{Group: "projects.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Project"}
+	group := schema.GroupVersionKind{Group: "groups.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Group"}
+	ImportAttempted(project)
+	ImportAttempted(project)
+	ImportAttempted(group)
+	ImportSucceeded(project)
+	ImportFailed(project)
+	ImportFailed(group)
+	PagesFetched(1)
+	PagesFetched(3)
+	CacheHit()
+	RunFunctionDone(time.Now())
+
+	want := `
+# HELP function_gitlab_importer_imports_attempted_total Number of composed resources the Function tried to import.
+# TYPE function_gitlab_importer_imports_attempted_total counter
+function_gitlab_importer_imports_attempted_total{group="groups.gitlab.crossplane.io",kind="Group",version="v1alpha1"} 1
+function_gitlab_importer_imports_attempted_total{group="projects.gitlab.crossplane.io",kind="Project",version="v1alpha1"} 2
+# HELP function_gitlab_importer_imports_succeeded_total Number of composed resources the Function imported.
+# TYPE function_gitlab_importer_imports_succeeded_total counter
+function_gitlab_importer_imports_succeeded_total{group="projects.gitlab.crossplane.io",kind="Project",version="v1alpha1"} 1
+# HELP function_gitlab_importer_imports_failed_total Number of composed resources the Function failed to import.
+# TYPE function_gitlab_importer_imports_failed_total counter
+function_gitlab_importer_imports_failed_total{group="groups.gitlab.crossplane.io",kind="Group",version="v1alpha1"} 1
+function_gitlab_importer_imports_failed_total{group="projects.gitlab.crossplane.io",kind="Project",version="v1alpha1"} 1
+# HELP function_gitlab_importer_lookup_pages Number of pages fetched per GitLab listing.
+# TYPE function_gitlab_importer_lookup_pages histogram
+function_gitlab_importer_lookup_pages_bucket{le="1"} 1
+function_gitlab_importer_lookup_pages_bucket{le="2"} 1
+function_gitlab_importer_lookup_pages_bucket{le="5"} 2
+function_gitlab_importer_lookup_pages_bucket{le="10"} 2
+function_gitlab_importer_lookup_pages_bucket{le="20"} 2
+function_gitlab_importer_lookup_pages_bucket{le="50"} 2
+function_gitlab_importer_lookup_pages_bucket{le="100"} 2
+function_gitlab_importer_lookup_pages_bucket{le="+Inf"} 2
+function_gitlab_importer_lookup_pages_sum 4
+function_gitlab_importer_lookup_pages_count 2
+# HELP function_gitlab_importer_lookup_cache_hits_total Number of imports served from a listing fetched earlier within the same request.
+# TYPE function_gitlab_importer_lookup_cache_hits_total counter
+function_gitlab_importer_lookup_cache_hits_total 1
+`
+	names := []string{
+		"function_gitlab_importer_imports_attempted_total",
+		"function_gitlab_importer_imports_succeeded_total",
+		"function_gitlab_importer_imports_failed_total",
+		"function_gitlab_importer_lookup_pages",
+		"function_gitlab_importer_lookup_cache_hits_total",
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), names...); err != nil {
+		t.Errorf("unexpected metrics:\n%v", err)
+	}
+
+	// durations cannot be predicted, so only the observations are counted
+	if diff := cmp.Diff(1, testutil.CollectAndCount(runFunctionDuration)); diff != "" {
+		t.Errorf("RunFunctionDone(...): -want series, +got series:\n%s", diff)
+	}
+	if diff := cmp.Diff(uint64(1), sampleCount(t, reg, "function_gitlab_importer_run_function_duration_seconds")); diff != "" {
+		t.Errorf("RunFunctionDone(...): -want observations, +got observations:\n%s", diff)
+	}
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(gitlabRequestDuration)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/42" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: InstrumentTransport(http.DefaultTransport)}
+	for _, path := range []string{"/api/v4/projects/42", "/api/v4/projects/42", "/api/v4/projects/43"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("client.Get(%s): %v", path, err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	// every request should be observed by its method and status code
+	got := map[string]uint64{}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("reg.Gather(): %v", err)
+	}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			labels := []string{}
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetName()+"="+l.GetValue())
+			}
+			got[strings.Join(labels, ",")] = m.GetHistogram().GetSampleCount()
+		}
+	}
+	want := map[string]uint64{"code=200,method=get": 2, "code=404,method=get": 1}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("InstrumentTransport(...): -want observations, +got observations:\n%s", diff)
+	}
+}
+
+// sampleCount returns the number of observations of the histogram name
+// gathered by reg.
+func sampleCount(t *testing.T, reg prometheus.Gatherer, name string) uint64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("reg.Gather(): %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() == name {
+			return f.GetMetric()[0].GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
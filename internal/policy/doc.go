@@ -0,0 +1,9 @@
+// Package policy resolves settings of the Function input that can be
+// overridden for selected composed resources, such as the error policy or the
+// management policies.
+//
+// Overrides select composed resources by their kind and composition resource
+// name or name pattern. If several overrides select the same resource, the
+// most specific one wins: a selected name beats a name pattern, which beats a
+// selected kind, which beats the default.
+package policy
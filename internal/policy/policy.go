@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"path"
+
+	"github.com/crossplane/crossplane-runtime/v2/apis/common"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Specificity of a selector. Higher values win over lower ones.
+const (
+	// NoMatch is returned for selectors that do not select a resource.
+	NoMatch = -1
+	// MatchAny is returned for selectors without any criteria.
+	MatchAny = 0
+	// MatchKind is returned for selectors that select by kind only.
+	MatchKind = 1
+	// MatchNamePattern is returned for selectors that select by name
+	// pattern.
+	MatchNamePattern = 2
+	// MatchName is returned for selectors that select by name.
+	MatchName = 3
+)
+
+// Select returns how specifically the selector selects the composed resource
+// with the given GVK and name, or NoMatch if it does not select it at all.
+func Select(sel v1beta1.ResourceSelector, gvk schema.GroupVersionKind, name resource.Name) int {
+	if sel.APIVersion != "" && sel.APIVersion != gvk.GroupVersion().String() {
+		return NoMatch
+	}
+	if sel.Kind != "" && sel.Kind != gvk.Kind {
+		return NoMatch
+	}
+	if sel.Name != "" && sel.Name != string(name) {
+		return NoMatch
+	}
+	if sel.NamePattern != "" {
+		// invalid patterns do not match any name
+		if ok, _ := path.Match(sel.NamePattern, string(name)); !ok {
+			return NoMatch
+		}
+	}
+
+	switch {
+	case sel.Name != "":
+		return MatchName
+	case sel.NamePattern != "":
+		return MatchNamePattern
+	case sel.APIVersion != "" || sel.Kind != "":
+		return MatchKind
+	default:
+		return MatchAny
+	}
+}
+
+// ErrorPolicy returns the error policy of the composed resource with the given
+// GVK and name. It defaults to Warn if neither an override nor a default is set.
+func ErrorPolicy(in *v1beta1.Input, gvk schema.GroupVersionKind, name resource.Name) v1beta1.ErrorPolicy {
+	errorPolicy := in.ErrorPolicy
+	if errorPolicy == "" {
+		errorPolicy = v1beta1.ErrorPolicyWarn
+	}
+
+	best := NoMatch
+	for _, o := range in.ErrorPolicyOverrides {
+		if s := Select(o.ResourceSelector, gvk, name); s > best {
+			best = s
+			errorPolicy = o.Policy
+		}
+	}
+	return errorPolicy
+}
+
+// Management describes how an imported resource is managed.
+type Management struct {
+	// Policies are the management policies of the resource.
+	Policies common.ManagementPolicies
+	// DeletionPolicy of the resource. It is left untouched if empty.
+	DeletionPolicy common.DeletionPolicy
+}
+
+// ManagementOf returns how the composed resource with the given GVK and name
+// is managed, according to the most specific management rule selecting it.
+// The management policies default to those of the input, or observe-only if
+// the input does not set any, and the deletion policy to that of the input.
+func ManagementOf(in *v1beta1.Input, gvk schema.GroupVersionKind, name resource.Name) Management {
+	defaults := Management{Policies: common.ManagementPolicies{common.ManagementActionObserve}, DeletionPolicy: in.DeletionPolicy}
+	if len(in.ManagementPolicies) > 0 {
+		defaults.Policies = in.ManagementPolicies
+	}
+
+	m := defaults
+	best := NoMatch
+	for _, r := range in.ManagementRules {
+		if s := Select(r.ResourceSelector, gvk, name); s > best {
+			best = s
+			m = defaults
+			if len(r.ManagementPolicies) > 0 {
+				m.Policies = r.ManagementPolicies
+			}
+			if r.DeletionPolicy != "" {
+				m.DeletionPolicy = r.DeletionPolicy
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/apis/common"
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestErrorPolicy(t *testing.T) {
+	projectGVK := schema.GroupVersionKind{Group: "projects.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Project"}
+
+	type args struct {
+		in   *v1beta1.Input
+		name resource.Name
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   v1beta1.ErrorPolicy
+	}{
+		"DefaultToWarn": {
+			reason: "Without any configuration failed imports should be warned about.",
+			args: args{
+				in:   &v1beta1.Input{},
+				name: "project",
+			},
+			want: v1beta1.ErrorPolicyWarn,
+		},
+		"Default": {
+			reason: "The configured default should be used if no override selects the resource.",
+			args: args{
+				in: &v1beta1.Input{
+					ErrorPolicy: v1beta1.ErrorPolicyContinue,
+					ErrorPolicyOverrides: []v1beta1.ErrorPolicyOverride{
+						{ResourceSelector: v1beta1.ResourceSelector{Kind: "Group"}, Policy: v1beta1.ErrorPolicyFatal},
+					},
+				},
+				name: "project",
+			},
+			want: v1beta1.ErrorPolicyContinue,
+		},
+		"OverrideByKind": {
+			reason: "An override selecting the kind should beat the default.",
+			args: args{
+				in: &v1beta1.Input{
+					ErrorPolicy: v1beta1.ErrorPolicyContinue,
+					ErrorPolicyOverrides: []v1beta1.ErrorPolicyOverride{
+						{ResourceSelector: v1beta1.ResourceSelector{APIVersion: "projects.gitlab.crossplane.io/v1alpha1", Kind: "Project"}, Policy: v1beta1.ErrorPolicyWarn},
+					},
+				},
+				name: "project",
+			},
+			want: v1beta1.ErrorPolicyWarn,
+		},
+		"OverrideByName": {
+			reason: "An override selecting the name should beat an override selecting the kind.",
+			args: args{
+				in: &v1beta1.Input{
+					ErrorPolicyOverrides: []v1beta1.ErrorPolicyOverride{
+						{ResourceSelector: v1beta1.ResourceSelector{Name: "critical-project"}, Policy: v1beta1.ErrorPolicyFatal},
+						{ResourceSelector: v1beta1.ResourceSelector{Kind: "Project"}, Policy: v1beta1.ErrorPolicyContinue},
+					},
+				},
+				name: "critical-project",
+			},
+			want: v1beta1.ErrorPolicyFatal,
+		},
+		"OverrideByNamePattern": {
+			reason: "An override matching the name pattern should beat an override selecting the kind.",
+			args: args{
+				in: &v1beta1.Input{
+					ErrorPolicyOverrides: []v1beta1.ErrorPolicyOverride{
+						{ResourceSelector: v1beta1.ResourceSelector{Kind: "Project"}, Policy: v1beta1.ErrorPolicyContinue},
+						{ResourceSelector: v1beta1.ResourceSelector{NamePattern: "critical-*"}, Policy: v1beta1.ErrorPolicyFatal},
+						{ResourceSelector: v1beta1.ResourceSelector{NamePattern: "other-*"}, Policy: v1beta1.ErrorPolicyWarn},
+					},
+				},
+				name: "critical-project",
+			},
+			want: v1beta1.ErrorPolicyFatal,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ErrorPolicy(tc.args.in, projectGVK, tc.args.name)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nErrorPolicy(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestManagementOf(t *testing.T) {
+	groupGVK := schema.GroupVersionKind{Group: "groups.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Group"}
+	projectGVK := schema.GroupVersionKind{Group: "projects.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Project"}
+	observe := common.ManagementPolicies{common.ManagementActionObserve}
+	update := common.ManagementPolicies{common.ManagementActionObserve, common.ManagementActionUpdate}
+	all := common.ManagementPolicies{common.ManagementActionAll}
+
+	rules := []v1beta1.ManagementRule{
+		{ResourceSelector: v1beta1.ResourceSelector{Kind: "Project"}, ManagementPolicies: update},
+		{ResourceSelector: v1beta1.ResourceSelector{NamePattern: "sandbox-*"}, ManagementPolicies: all, DeletionPolicy: common.DeletionDelete},
+		{ResourceSelector: v1beta1.ResourceSelector{Name: "sandbox-production"}, DeletionPolicy: common.DeletionOrphan},
+	}
+
+	type args struct {
+		in   *v1beta1.Input
+		gvk  schema.GroupVersionKind
+		name resource.Name
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   Management
+	}{
+		"DefaultToObserve": {
+			reason: "Without any configuration imported resources should be observed only.",
+			args:   args{in: &v1beta1.Input{}, gvk: projectGVK, name: "project"},
+			want:   Management{Policies: observe},
+		},
+		"Default": {
+			reason: "The management policies of the input should be used if no rule selects the resource.",
+			args:   args{in: &v1beta1.Input{ManagementPolicies: update, ManagementRules: rules}, gvk: groupGVK, name: "group"},
+			want:   Management{Policies: update},
+		},
+		"RuleByKind": {
+			reason: "A rule selecting the kind should beat the default.",
+			args:   args{in: &v1beta1.Input{ManagementRules: rules}, gvk: projectGVK, name: "project"},
+			want:   Management{Policies: update},
+		},
+		"RuleByNamePattern": {
+			reason: "A rule matching the name pattern should beat a rule selecting the kind.",
+			args:   args{in: &v1beta1.Input{ManagementRules: rules}, gvk: projectGVK, name: "sandbox-test"},
+			want:   Management{Policies: all, DeletionPolicy: common.DeletionDelete},
+		},
+		"RuleByName": {
+			reason: "A rule selecting the name should win and default to the management policies of the input.",
+			args:   args{in: &v1beta1.Input{ManagementRules: rules}, gvk: projectGVK, name: "sandbox-production"},
+			want:   Management{Policies: observe, DeletionPolicy: common.DeletionOrphan},
+		},
+		"DefaultDeletionPolicy": {
+			reason: "The deletion policy of the input should be used unless the rule selecting the resource sets one.",
+			args:   args{in: &v1beta1.Input{DeletionPolicy: common.DeletionOrphan, ManagementRules: rules}, gvk: projectGVK, name: "project"},
+			want:   Management{Policies: update, DeletionPolicy: common.DeletionOrphan},
+		},
+		"RuleOverridesDeletionPolicy": {
+			reason: "The deletion policy of the rule selecting the resource should beat that of the input.",
+			args:   args{in: &v1beta1.Input{DeletionPolicy: common.DeletionOrphan, ManagementRules: rules}, gvk: projectGVK, name: "sandbox-test"},
+			want:   Management{Policies: all, DeletionPolicy: common.DeletionDelete},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ManagementOf(tc.args.in, tc.args.gvk, tc.args.name)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nManagementOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
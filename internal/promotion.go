@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Annotations tracking the promotion of an imported composed resource from
+// observe-only to its management policies.
+const (
+	AnnotationPromotionMatches       = "gitlab-importer.fn.crossplane.io/promotion-matches"
+	AnnotationPromotionMatchingSince = "gitlab-importer.fn.crossplane.io/promotion-matching-since"
+	AnnotationPromotedAt             = "gitlab-importer.fn.crossplane.io/promoted-at"
+)
+
+// Promotion is the progress of an imported composed resource promoted from
+// observe-only to its management policies.
+type Promotion struct {
+	// Matches is the number of consecutive reconciles the observed state
+	// has matched the desired state.
+	Matches int
+	// MatchingSince is the time the observed state started to match the
+	// desired state. It is zero while they do not match.
+	MatchingSince time.Time
+	// PromotedAt is the time the resource has been promoted. It is zero
+	// until the resource is promoted.
+	PromotedAt time.Time
+}
+
+// Promoted returns true if the resource has been promoted.
+func (p Promotion) Promoted() bool {
+	return !p.PromotedAt.IsZero()
+}
+
+// Next returns the progress after a reconcile at which the observed state
+// did or did not match the desired state. The resource is promoted once it
+// reached every threshold configured. Promoted resources stay promoted.
+func (p Promotion) Next(cfg *v1beta1.Promotion, matches bool, now time.Time) Promotion {
+	if p.Promoted() {
+		return p
+	}
+	if !matches {
+		return Promotion{}
+	}
+	p.Matches++
+	if p.MatchingSince.IsZero() {
+		p.MatchingSince = now
+	}
+	if cfg.Reconciles > 0 && p.Matches < cfg.Reconciles {
+		return p
+	}
+	if cfg.Duration != nil && now.Sub(p.MatchingSince) < cfg.Duration.Duration {
+		return p
+	}
+	p.PromotedAt = now
+	return p
+}
+
+// GetPromotionFromObserved returns the promotion progress recorded on an
+// observed composed resource. It returns false if the resource has not been
+// imported under promotion.
+func GetPromotionFromObserved(obs resource.ObservedComposed) (Promotion, bool, error) {
+	annotations := obs.Resource.GetAnnotations()
+	matches, ok := annotations[AnnotationPromotionMatches]
+	if !ok {
+		return Promotion{}, false, nil
+	}
+	p := Promotion{}
+	var err error
+	if p.Matches, err = strconv.Atoi(matches); err != nil {
+		return Promotion{}, true, errors.Errorf("cannot parse annotation %s: %w", AnnotationPromotionMatches, err)
+	}
+	if p.MatchingSince, err = parseTimeAnnotation(annotations, AnnotationPromotionMatchingSince); err != nil {
+		return Promotion{}, true, err
+	}
+	if p.PromotedAt, err = parseTimeAnnotation(annotations, AnnotationPromotedAt); err != nil {
+		return Promotion{}, true, err
+	}
+	return p, true, nil
+}
+
+// SetPromotionOnDesired records the promotion progress on a desired composed
+// resource. Zero times are left out.
+func SetPromotionOnDesired(des *resource.DesiredComposed, p Promotion) {
+	AddAnnotationOnDesired(des, AnnotationPromotionMatches, strconv.Itoa(p.Matches))
+	if !p.MatchingSince.IsZero() {
+		AddAnnotationOnDesired(des, AnnotationPromotionMatchingSince, p.MatchingSince.UTC().Format(time.RFC3339))
+	}
+	if !p.PromotedAt.IsZero() {
+		AddAnnotationOnDesired(des, AnnotationPromotedAt, p.PromotedAt.UTC().Format(time.RFC3339))
+	}
+}
+
+// ObservedMatchesDesired returns whether the observed state of a composed
+// resource matches its desired state: the resource has to be synced and the
+// desired spec.forProvider must not drift from the GitLab resource obj, as
+// returned by the GitLab API. The status.atProvider of provider-gitlab lacks
+// most fields, so it is not compared. A resource none of whose fields can be
+// compared never matches. Otherwise the reason is returned.
+func ObservedMatchesDesired(obs resource.ObservedComposed, des *resource.DesiredComposed, obj map[string]any) (bool, string) {
+	synced := obs.Resource.GetCondition("Synced")
+	if synced.Status != corev1.ConditionTrue {
+		return false, fmt.Sprintf("resource is not synced: %s", synced.Message)
+	}
+
+	drift, compared := compare(des, obj)
+	if compared == 0 {
+		return false, "no field of spec.forProvider can be compared with the GitLab resource"
+	}
+	if len(drift) > 0 {
+		fields := make([]string, 0, len(drift))
+		for _, d := range drift {
+			fields = append(fields, d.String())
+		}
+		return false, strings.Join(fields, "; ")
+	}
+	return true, ""
+}
+
+// parseTimeAnnotation parses the RFC3339 time of an annotation. Missing
+// annotations are returned as zero time.
+func parseTimeAnnotation(annotations map[string]string, key string) (time.Time, error) {
+	value, ok := annotations[key]
+	if !ok {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("cannot parse annotation %s: %w", key, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestPromotionNext(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour)
+
+	type args struct {
+		cfg      v1beta1.Promotion
+		progress Promotion
+		matches  bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   Promotion
+	}{
+		"FirstMatch": {
+			reason: "The first match should start the progress.",
+			args:   args{cfg: v1beta1.Promotion{Reconciles: 3}, matches: true},
+			want:   Promotion{Matches: 1, MatchingSince: now},
+		},
+		"Mismatch": {
+			reason: "A mismatch should reset the progress.",
+			args: args{
+				cfg:      v1beta1.Promotion{Reconciles: 3},
+				progress: Promotion{Matches: 2, MatchingSince: start},
+			},
+			want: Promotion{},
+		},
+		"ReconcilesReached": {
+			reason: "The resource should be promoted once it matched for the given number of reconciles.",
+			args: args{
+				cfg:      v1beta1.Promotion{Reconciles: 3},
+				progress: Promotion{Matches: 2, MatchingSince: start},
+				matches:  true,
+			},
+			want: Promotion{Matches: 3, MatchingSince: start, PromotedAt: now},
+		},
+		"DurationNotReached": {
+			reason: "The resource should not be promoted before every threshold is reached.",
+			args: args{
+				cfg:      v1beta1.Promotion{Reconciles: 3, Duration: &metav1.Duration{Duration: 2 * time.Hour}},
+				progress: Promotion{Matches: 2, MatchingSince: start},
+				matches:  true,
+			},
+			want: Promotion{Matches: 3, MatchingSince: start},
+		},
+		"DurationReached": {
+			reason: "The resource should be promoted once it matched for the given duration.",
+			args: args{
+				cfg:      v1beta1.Promotion{Duration: &metav1.Duration{Duration: time.Hour}},
+				progress: Promotion{Matches: 1, MatchingSince: start},
+				matches:  true,
+			},
+			want: Promotion{Matches: 2, MatchingSince: start, PromotedAt: now},
+		},
+		"StayPromoted": {
+			reason: "A promoted resource should stay promoted even if it does not match anymore.",
+			args: args{
+				cfg:      v1beta1.Promotion{Reconciles: 3},
+				progress: Promotion{Matches: 3, MatchingSince: start, PromotedAt: start},
+			},
+			want: Promotion{Matches: 3, MatchingSince: start, PromotedAt: start},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.args.progress.Next(&tc.args.cfg, tc.args.matches, now)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\np.Next(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetPromotionFromObserved(t *testing.T) {
+	type want struct {
+		p       Promotion
+		tracked bool
+		err     bool
+	}
+
+	cases := map[string]struct {
+		reason      string
+		annotations map[string]string
+		want        want
+	}{
+		"NotTracked": {
+			reason:      "Resources without promotion progress have not been imported under promotion.",
+			annotations: map[string]string{"crossplane.io/external-name": "42"},
+			want:        want{},
+		},
+		"Progress": {
+			reason: "The recorded progress should be returned.",
+			annotations: map[string]string{
+				AnnotationPromotionMatches:       "2",
+				AnnotationPromotionMatchingSince: "2024-01-01T00:00:00Z",
+			},
+			want: want{p: Promotion{Matches: 2, MatchingSince: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, tracked: true},
+		},
+		"Promoted": {
+			reason: "The time of the promotion should be returned.",
+			annotations: map[string]string{
+				AnnotationPromotionMatches: "3",
+				AnnotationPromotedAt:       "2024-01-02T00:00:00Z",
+			},
+			want: want{p: Promotion{Matches: 3, PromotedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, tracked: true},
+		},
+		"InvalidMatches": {
+			reason:      "An invalid number of matches should be an error.",
+			annotations: map[string]string{AnnotationPromotionMatches: "many"},
+			want:        want{tracked: true, err: true},
+		},
+		"InvalidTime": {
+			reason: "An invalid time should be an error.",
+			annotations: map[string]string{
+				AnnotationPromotionMatches:       "1",
+				AnnotationPromotionMatchingSince: "yesterday",
+			},
+			want: want{tracked: true, err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			obs := resource.ObservedComposed{Resource: composed.New()}
+			obs.Resource.SetAnnotations(tc.annotations)
+			p, tracked, err := GetPromotionFromObserved(obs)
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Errorf("%s\nGetPromotionFromObserved(...): -want err, +got err:\n%s\n%v", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(tc.want.tracked, tracked); diff != "" {
+				t.Errorf("%s\nGetPromotionFromObserved(...): -want tracked, +got tracked:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.p, p); diff != "" {
+				t.Errorf("%s\nGetPromotionFromObserved(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObservedMatchesDesired(t *testing.T) {
+	synced := []any{map[string]any{"type": "Synced", "status": "True", "reason": "ReconcileSuccess"}}
+
+	type args struct {
+		conditions  []any
+		forProvider map[string]any
+		obj         map[string]any
+	}
+	type want struct {
+		matches bool
+		reason  string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Matches": {
+			reason: "A synced resource without drift from GitLab should match.",
+			args: args{
+				conditions:  synced,
+				forProvider: map[string]any{"visibility": "private", "namespaceId": float64(1)},
+				obj:         map[string]any{"visibility": "private"},
+			},
+			want: want{matches: true},
+		},
+		"NotSynced": {
+			reason: "A resource that is not synced should not match.",
+			args: args{
+				conditions:  []any{map[string]any{"type": "Synced", "status": "False", "reason": "ReconcileError", "message": "boom"}},
+				forProvider: map[string]any{"visibility": "private"},
+				obj:         map[string]any{"visibility": "private"},
+			},
+			want: want{reason: "resource is not synced: boom"},
+		},
+		"Drift": {
+			reason: "A resource drifting from GitLab should not match.",
+			args: args{
+				conditions:  synced,
+				forProvider: map[string]any{"visibility": "public", "defaultBranch": "main"},
+				obj:         map[string]any{"visibility": "private", "default_branch": "main"},
+			},
+			want: want{reason: `visibility: "private" in GitLab, desired "public"`},
+		},
+		"NothingCompared": {
+			reason: "A resource none of whose fields can be compared should not match.",
+			args: args{
+				conditions:  synced,
+				forProvider: map[string]any{"namespaceId": float64(1)},
+				obj:         map[string]any{"visibility": "private"},
+			},
+			want: want{reason: "no field of spec.forProvider can be compared with the GitLab resource"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			obs := resource.ObservedComposed{Resource: composed.New()}
+			if err := obs.Resource.SetValue("status.conditions", tc.args.conditions); err != nil {
+				t.Fatalf("cannot set status.conditions: %v", err)
+			}
+			des := &resource.DesiredComposed{Resource: composed.New()}
+			if err := des.Resource.SetValue("spec.forProvider", tc.args.forProvider); err != nil {
+				t.Fatalf("cannot set spec.forProvider: %v", err)
+			}
+			matches, reason := ObservedMatchesDesired(obs, des, tc.args.obj)
+			if diff := cmp.Diff(tc.want, want{matches: matches, reason: reason}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nObservedMatchesDesired(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
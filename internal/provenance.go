@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Annotations recording the provenance of an imported composed resource.
+const (
+	AnnotationFullPath       = "gitlab-importer.fn.crossplane.io/full-path"
+	AnnotationWebURL         = "gitlab-importer.fn.crossplane.io/web-url"
+	AnnotationImportedAt     = "gitlab-importer.fn.crossplane.io/imported-at"
+	AnnotationImportedBy     = "gitlab-importer.fn.crossplane.io/imported-by"
+	AnnotationLookupStrategy = "gitlab-importer.fn.crossplane.io/lookup-strategy"
+)
+
+// ProvenanceAnnotations are all annotations set by SetProvenanceOnDesired.
+var ProvenanceAnnotations = []string{
+	AnnotationFullPath,
+	AnnotationWebURL,
+	AnnotationImportedAt,
+	AnnotationImportedBy,
+	AnnotationLookupStrategy,
+}
+
+// Provenance describes where an imported composed resource came from.
+type Provenance struct {
+	FullPath       string
+	WebURL         string
+	ImportedAt     time.Time
+	ImportedBy     string
+	LookupStrategy string
+}
+
+// SetProvenanceOnDesired annotates a desired composed resource with its
+// provenance. Empty values are left out.
+func SetProvenanceOnDesired(des *resource.DesiredComposed, p Provenance) {
+	values := map[string]string{
+		AnnotationFullPath:       p.FullPath,
+		AnnotationWebURL:         p.WebURL,
+		AnnotationImportedBy:     p.ImportedBy,
+		AnnotationLookupStrategy: p.LookupStrategy,
+	}
+	if !p.ImportedAt.IsZero() {
+		values[AnnotationImportedAt] = p.ImportedAt.UTC().Format(time.RFC3339)
+	}
+	for _, key := range ProvenanceAnnotations {
+		if values[key] != "" {
+			AddAnnotationOnDesired(des, key, values[key])
+		}
+	}
+}
+
+// CopyAnnotationsFromObserved copies the given annotations from an observed
+// to a desired composed resource. Annotations missing on the observed
+// resource are skipped.
+func CopyAnnotationsFromObserved(obs resource.ObservedComposed, des *resource.DesiredComposed, keys ...string) {
+	annotations := obs.Resource.GetAnnotations()
+	for _, key := range keys {
+		if value, ok := annotations[key]; ok {
+			AddAnnotationOnDesired(des, key, value)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+// Package tracing instruments the Function with OpenTelemetry spans.
+//
+// Spans are always created using the global tracer provider. Unless Setup has
+// been called with an exporter, the provider is a no-op and spans are
+// dropped.
+package tracing
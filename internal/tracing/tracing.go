@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// instrumentationName identifies the spans created by the Function.
+const instrumentationName = "github.com/simon-fredrich/function-gitlab-importer"
+
+// Exporters supported by Setup.
+const (
+	// ExporterNone drops all spans.
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OTLP collector using gRPC.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans to stdout, which is useful for local use.
+	ExporterStdout = "stdout"
+)
+
+// Attribute keys set on the spans of the Function.
+const (
+	AttrResourceName  = attribute.Key("crossplane.resource.name")
+	AttrGVK           = attribute.Key("crossplane.resource.gvk")
+	AttrNamespaceID   = attribute.Key("gitlab.namespace.id")
+	AttrPage          = attribute.Key("gitlab.page")
+	AttrLookupBackend = attribute.Key("gitlab.lookup.backend")
+	AttrPending       = attribute.Key("gitlab.lookup.pending")
+)
+
+// Options configure the exporter set up by Setup.
+type Options struct {
+	// Exporter is one of ExporterNone, ExporterOTLP and ExporterStdout.
+	Exporter string
+	// Endpoint of the OTLP collector. The OTEL_EXPORTER_OTLP_* environment
+	// variables are used if it is empty.
+	Endpoint string
+	// Insecure disables TLS towards the OTLP collector.
+	Insecure bool
+	// Version of the Function recorded on every span.
+	Version string
+}
+
+// Setup installs a global tracer provider exporting spans as configured by
+// the options. It returns a function flushing all pending spans, which has to
+// be called before the Function exits.
+func Setup(ctx context.Context, o Options) (func(context.Context) error, error) {
+	var processor sdktrace.TracerProviderOption
+	switch o.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{}
+		if o.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(o.Endpoint))
+		}
+		if o.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		e, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, errors.Errorf("cannot create otlp exporter: %w", err)
+		}
+		processor = sdktrace.WithBatcher(e)
+	case ExporterStdout:
+		e, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, errors.Errorf("cannot create stdout exporter: %w", err)
+		}
+		// write every span right away, so none get lost when stopping locally
+		processor = sdktrace.WithSyncer(e)
+	default:
+		return nil, errors.Errorf("unknown tracing exporter %q", o.Exporter)
+	}
+
+	res, err := sdkresource.Merge(sdkresource.Default(), sdkresource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("function-gitlab-importer"),
+		semconv.ServiceVersion(o.Version),
+	))
+	if err != nil {
+		return nil, errors.Errorf("cannot create tracing resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(processor, sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp.Shutdown, nil
+}
+
+// Start starts a span with the given attributes as child of the span within
+// ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if it is not nil, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// GVK returns the attribute describing a GVK.
+func GVK(gvk schema.GroupVersionKind) attribute.KeyValue {
+	return AttrGVK.String(gvk.String())
+}
+
+// InstrumentTransport returns a RoundTripper that creates a span for every
+// request sent through next.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return "GitLab " + r.Method
+	}))
+}
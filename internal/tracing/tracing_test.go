@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// span is the part of a recorded span the tests compare.
+type span struct {
+	Name        string
+	Attributes  []attribute.KeyValue
+	Status      codes.Code
+	Description string
+	Parent      string
+}
+
+// record installs a global tracer provider recording every span and restores
+// the previous provider once the test is done.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestStartEnd(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "projects.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Project"}
+
+	type args struct {
+		name  string
+		attrs []attribute.KeyValue
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   span
+	}{
+		"Ok": {
+			reason: "A span should be recorded with its name and attributes.",
+			args: args{
+				name:  "ImportResource",
+				attrs: []attribute.KeyValue{AttrResourceName.String("project"), GVK(gvk)},
+			},
+			want: span{
+				Name: "ImportResource",
+				Attributes: []attribute.KeyValue{
+					AttrResourceName.String("project"),
+					AttrGVK.String("projects.gitlab.crossplane.io/v1alpha1, Kind=Project"),
+				},
+				Status: codes.Unset,
+				Parent: "RunFunction",
+			},
+		},
+		"Error": {
+			reason: "The error a span ended with should be recorded as its status.",
+			args: args{
+				name:  "ListNamespace",
+				attrs: []attribute.KeyValue{AttrNamespaceID.Int(42), AttrPage.Int(2)},
+				err:   errors.New("boom"),
+			},
+			want: span{
+				Name:        "ListNamespace",
+				Attributes:  []attribute.KeyValue{AttrNamespaceID.Int(42), AttrPage.Int(2)},
+				Status:      codes.Error,
+				Description: "boom",
+				Parent:      "RunFunction",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rec := record(t)
+
+			ctx, parent := Start(context.Background(), "RunFunction")
+			_, s := Start(ctx, tc.args.name, tc.args.attrs...)
+			End(s, tc.args.err)
+			End(parent, nil)
+
+			ended := rec.Ended()
+			if diff := cmp.Diff(2, len(ended)); diff != "" {
+				t.Fatalf("%s\nStart(...): -want spans, +got spans:\n%s", tc.reason, diff)
+			}
+			got := span{
+				Name:        ended[0].Name(),
+				Attributes:  ended[0].Attributes(),
+				Status:      ended[0].Status().Code,
+				Description: ended[0].Status().Description,
+			}
+			if ended[0].Parent().SpanID() == ended[1].SpanContext().SpanID() {
+				got.Parent = ended[1].Name()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.Comparer(func(a, b attribute.Value) bool { return a == b })); diff != "" {
+				t.Errorf("%s\nStart(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	rec := record(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	client := &http.Client{Transport: InstrumentTransport(http.DefaultTransport)}
+
+	ctx, parent := Start(context.Background(), "ImportResource")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v4/groups/42/projects", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequestWithContext(...): %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do(...): %v", err)
+	}
+	_ = resp.Body.Close()
+	End(parent, nil)
+
+	// every request should be a child of the span it was sent within
+	got := []string{}
+	for _, s := range rec.Ended() {
+		if s.Parent().SpanID() == parent.SpanContext().SpanID() {
+			got = append(got, s.Name())
+		}
+	}
+	if diff := cmp.Diff([]string{"GitLab GET"}, got); diff != "" {
+		t.Errorf("InstrumentTransport(...): -want spans, +got spans:\n%s", diff)
+	}
+}
@@ -2,11 +2,40 @@
 package main
 
 import (
+	"context"
+	"runtime/debug"
+	"time"
+
 	"github.com/alecthomas/kong"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/audit"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/metrics"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/tracing"
 
 	"github.com/crossplane/function-sdk-go"
 )
 
+// version of this Function. It is set at build time using
+// -ldflags "-X main.version=<version>".
+var version string
+
+// functionVersion returns the version of this Function. It falls back to the
+// VCS revision the Function was built from if no version has been set.
+func functionVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return info.Main.Version
+}
+
 // CLI of this Function.
 type CLI struct {
 	Debug bool `help:"Emit debug logs in addition to info logs." short:"d"`
@@ -16,6 +45,11 @@ type CLI struct {
 	TLSCertsDir        string `env:"TLS_SERVER_CERTS_DIR"                                                                           help:"Directory containing server certs (tls.key, tls.crt) and the CA used to verify client certificates (ca.crt)"`
 	Insecure           bool   `help:"Run without mTLS credentials. If you supply this flag --tls-server-certs-dir will be ignored."`
 	MaxRecvMessageSize int    `default:"4"                                                                                          help:"Maximum size of received messages in MB."`
+	MetricsAddress     string `env:"METRICS_ADDRESS"                                                                                help:"Address at which to expose Prometheus metrics on /metrics. Metrics are not exposed if empty."`
+	TracingExporter    string `default:"none"                                                                                       enum:"none,otlp,stdout" env:"TRACING_EXPORTER" help:"Exporter for OpenTelemetry spans: none, otlp or stdout."`
+	OTLPEndpoint       string `env:"OTLP_ENDPOINT"                                                                                  help:"Endpoint (host:port) of the OTLP collector receiving spans. Defaults to the OTEL_EXPORTER_OTLP_* environment variables."`
+	OTLPInsecure       bool   `env:"OTLP_INSECURE"                                                                                  help:"Send spans to the OTLP collector without TLS."`
+	AuditLog           string `env:"AUDIT_LOG"                                                                                      help:"JSON Lines file to record every decision of the Function to, or - for stdout. Decisions are not recorded if empty."`
 }
 
 // Run this Function.
@@ -25,7 +59,42 @@ func (c *CLI) Run() error {
 		return err
 	}
 
-	return function.Serve(&Function{log: log},
+	if c.MetricsAddress != "" {
+		if err := metrics.Serve(c.MetricsAddress); err != nil {
+			return err
+		}
+		log.Info("Serving metrics", "address", c.MetricsAddress)
+	}
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter: c.TracingExporter,
+		Endpoint: c.OTLPEndpoint,
+		Insecure: c.OTLPInsecure,
+		Version:  functionVersion(),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// flush pending spans before exiting
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			log.Info("Cannot flush spans", "error", err)
+		}
+	}()
+
+	f := &Function{log: log, version: functionVersion()}
+	if c.AuditLog != "" {
+		sink, err := audit.Open(c.AuditLog)
+		if err != nil {
+			return err
+		}
+		defer sink.Close() //nolint:errcheck // nothing left to do when exiting
+		f.audit = sink
+	}
+
+	return function.Serve(f,
 		function.Listen(c.Network, c.Address),
 		function.MTLSCertificates(c.TLSCertsDir),
 		function.Insecure(c.Insecure),
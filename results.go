@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/simon-fredrich/function-gitlab-importer/input/v1beta1"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/policy"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/response"
+)
+
+// outcomeStatus is the result of processing a single composed resource.
+type outcomeStatus string
+
+const (
+	outcomeImported outcomeStatus = "Imported"
+	outcomeSkipped  outcomeStatus = "Skipped"
+	outcomeFailed   outcomeStatus = "Failed"
+	// outcomeWouldImport is reported in dry run mode instead of
+	// outcomeImported.
+	outcomeWouldImport outcomeStatus = "WouldImport"
+	// outcomeBlocked is reported for resources whose import has been
+	// blocked by a guard, e.g. a false import condition.
+	outcomeBlocked outcomeStatus = "Blocked"
+	// outcomePending is reported for resources whose import waits for
+	// approval.
+	outcomePending outcomeStatus = "Pending"
+	// outcomeIgnored is only reported in explain mode, for resources that
+	// are not handled by the Function at all.
+	outcomeIgnored outcomeStatus = "Ignored"
+)
+
+// reasonDrift is the reason of results reporting the drift of an imported
+// resource from its GitLab resource.
+const reasonDrift = "Drift"
+
+// reasonUnmarked is the reason of results reporting an imported resource
+// whose GitLab resource carries no owner marker.
+const reasonUnmarked = "Unmarked"
+
+// outcome describes how a single composed resource has been processed.
+type outcome struct {
+	name   resource.Name
+	gvk    schema.GroupVersionKind
+	status outcomeStatus
+
+	// result is set if the resource has been imported. For resources skipped
+	// because they are already managed, only its external-name is set.
+	result importer.Result
+	// reason is set if the resource has been skipped.
+	reason string
+	// err is set if the resource could not be imported.
+	err error
+	// condition is the message of the condition reporting whether the
+	// external resource already exists, if it has been checked.
+	condition string
+	// managementPolicies are set on resources that would have been imported
+	// in dry run mode.
+	managementPolicies []string
+	// inspected are the values the outcome has been decided on. They are
+	// reported in explain mode.
+	inspected []inspection
+	// drift are the fields of an imported resource differing from its
+	// GitLab resource, if a drift report is requested.
+	drift driftReport
+	// unmarked is the reason why no owner marker is stamped on the GitLab
+	// resource of an imported resource, if there is none.
+	unmarked string
+}
+
+// inspection is a single value inspected while processing a resource.
+type inspection struct {
+	key   string
+	value string
+}
+
+// imported returns the outcome of a resource imported as result.
+func imported(name resource.Name, gvk schema.GroupVersionKind, result importer.Result) outcome {
+	return outcome{name: name, gvk: gvk, status: outcomeImported, result: result}
+}
+
+// wouldImport returns the outcome of a resource that would have been imported
+// as result with the given management policies, if it was not a dry run.
+func wouldImport(name resource.Name, gvk schema.GroupVersionKind, result importer.Result, managementPolicies []string) outcome {
+	return outcome{name: name, gvk: gvk, status: outcomeWouldImport, result: result, managementPolicies: managementPolicies}
+}
+
+// skipped returns the outcome of a resource that did not need to be imported.
+func skipped(name resource.Name, gvk schema.GroupVersionKind, reason string) outcome {
+	return outcome{name: name, gvk: gvk, status: outcomeSkipped, reason: reason}
+}
+
+// alreadyManaged returns the outcome of a resource skipped because it has
+// been imported before and its external-name is managed already.
+func alreadyManaged(name resource.Name, gvk schema.GroupVersionKind, externalName string) outcome {
+	return outcome{
+		name:   name,
+		gvk:    gvk,
+		status: outcomeSkipped,
+		result: importer.Result{ExternalName: externalName},
+		reason: fmt.Sprintf("external-name %s is already managed", externalName),
+	}
+}
+
+// blocked returns the outcome of a resource whose import has been blocked.
+func blocked(name resource.Name, gvk schema.GroupVersionKind, reason string) outcome {
+	return outcome{name: name, gvk: gvk, status: outcomeBlocked, reason: reason}
+}
+
+// pendingApproval returns the outcome of a resource whose import waits for approval.
+func pendingApproval(name resource.Name, gvk schema.GroupVersionKind, reason string) outcome {
+	return outcome{name: name, gvk: gvk, status: outcomePending, reason: reason}
+}
+
+// ignored returns the outcome of a resource the Function does not handle.
+func ignored(name resource.Name, gvk schema.GroupVersionKind, reason string) outcome {
+	return outcome{name: name, gvk: gvk, status: outcomeIgnored, reason: reason}
+}
+
+// failed returns the outcome of a resource that could not be imported.
+func failed(name resource.Name, gvk schema.GroupVersionKind, err error) outcome {
+	return outcome{name: name, gvk: gvk, status: outcomeFailed, err: err}
+}
+
+// withCondition returns the outcome with the given condition message.
+func (o outcome) withCondition(condition string) outcome {
+	o.condition = condition
+	return o
+}
+
+// withDrift returns the outcome with the given drift.
+func (o outcome) withDrift(drift driftReport) outcome {
+	o.drift = drift
+	return o
+}
+
+// withUnmarked returns the outcome with the reason why no owner marker is
+// stamped on its GitLab resource.
+func (o outcome) withUnmarked(reason string) outcome {
+	o.unmarked = reason
+	return o
+}
+
+// withCheck returns the outcome with the condition message and inspected
+// values of check, the outcome of checking whether to import the resource.
+func (o outcome) withCheck(check outcome) outcome {
+	o.condition = check.condition
+	o.inspected = append(append([]inspection{}, check.inspected...), o.inspected...)
+	return o
+}
+
+// inspect returns the outcome with the given values added to the inspected
+// ones.
+func (o outcome) inspect(values ...inspection) outcome {
+	o.inspected = append(append([]inspection{}, o.inspected...), values...)
+	return o
+}
+
+// managed returns true if the Function manages the external-name of the
+// resource, as it has been imported by this or an earlier request.
+func (o outcome) managed() bool {
+	return o.status == outcomeImported || o.status == outcomeSkipped && o.result.ExternalName != ""
+}
+
+// inspectLookup returns the outcome with the lookup strategy and candidates
+// of its result added to the inspected values.
+func (o outcome) inspectLookup() outcome {
+	candidates := make([]string, 0, len(o.result.Candidates))
+	for _, c := range o.result.Candidates {
+		candidates = append(candidates, fmt.Sprintf("%s (%s)", c.ID, c.FullPath))
+	}
+	return o.inspect(
+		inspection{key: "lookup strategy", value: o.result.Strategy},
+		inspection{key: "candidates", value: strings.Join(candidates, ", ")},
+	)
+}
+
+// explanation returns the message of the outcome followed by the GVK and all
+// values inspected to decide it.
+func (o outcome) explanation() string {
+	values := make([]string, 0, len(o.inspected)+1)
+	values = append(values, fmt.Sprintf("gvk=%q", o.gvk.String()))
+	for _, i := range o.inspected {
+		values = append(values, fmt.Sprintf("%s=%q", i.key, i.value))
+	}
+	return fmt.Sprintf("%s; inspected %s", o.message(), strings.Join(values, ", "))
+}
+
+// driftMessage returns a human readable description of the drift of the
+// outcome.
+func (o outcome) driftMessage() string {
+	if o.drift.err != nil {
+		return fmt.Sprintf("composed resource %q: drift from external resource %s (%s) unavailable: %s", o.name, o.result.ExternalName, o.result.FullPath, o.drift.err)
+	}
+	fields := make([]string, 0, len(o.drift.fields))
+	for _, d := range o.drift.fields {
+		fields = append(fields, d.String())
+	}
+	return fmt.Sprintf("composed resource %q: drift from external resource %s (%s): %s", o.name, o.result.ExternalName, o.result.FullPath, strings.Join(fields, "; "))
+}
+
+// unmarkedMessage returns a human readable description of why the GitLab
+// resource of the outcome carries no owner marker.
+func (o outcome) unmarkedMessage() string {
+	return fmt.Sprintf("composed resource %q: external resource %s (%s) carries no owner marker, so other composite resources may adopt it as well: %s", o.name, o.result.ExternalName, o.result.FullPath, o.unmarked)
+}
+
+// message returns a human readable description of the outcome.
+func (o outcome) message() string {
+	switch o.status {
+	case outcomeImported:
+		if o.result.FullPath == "" {
+			return fmt.Sprintf("composed resource %q: imported external resource %s", o.name, o.result.ExternalName)
+		}
+		return fmt.Sprintf("composed resource %q: imported external resource %s (%s)", o.name, o.result.ExternalName, o.result.FullPath)
+	case outcomeWouldImport:
+		return fmt.Sprintf("composed resource %q: dry run: would set external-name %s (%s) and managementPolicies [%s]",
+			o.name, o.result.ExternalName, o.result.FullPath, strings.Join(o.managementPolicies, ", "))
+	case outcomeSkipped:
+		return fmt.Sprintf("composed resource %q: skipped: %s", o.name, o.reason)
+	case outcomeBlocked:
+		return fmt.Sprintf("composed resource %q: import blocked: %s", o.name, o.reason)
+	case outcomePending:
+		return fmt.Sprintf("composed resource %q: pending approval: %s", o.name, o.reason)
+	case outcomeIgnored:
+		return fmt.Sprintf("composed resource %q: ignored: %s", o.name, o.reason)
+	default:
+		return fmt.Sprintf("composed resource %q: import failed: %s", o.name, o.err)
+	}
+}
+
+// sortOutcomes sorts outcomes by the name of their composed resource, so
+// results are reported in a stable order.
+func sortOutcomes(outcomes []outcome) {
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].name < outcomes[j].name
+	})
+}
+
+// setResults adds one result per outcome to the response and sets the
+// FunctionSuccess condition. In explain mode, every result names the values
+// inspected to decide it. Blocked imports are reported as warnings and pending
+// imports as normal results to the composite and claim, keeping the condition
+// true. The drift of imported resources is reported by an additional normal
+// result, or a warning if it is unavailable. Imported resources whose GitLab
+// resource carries no owner marker are reported by an additional warning.
+// Failed imports are reported
+// according to the error policy of their resource:
+//   - Continue: as normal result, keeping the condition true.
+//   - Warn: as warning, setting the condition to false.
+//   - Fatal: as fatal result, which stops the pipeline.
+func setResults(rsp *fnv1.RunFunctionResponse, in *v1beta1.Input, outcomes []outcome) {
+	failures, total := 0, 0
+	for _, o := range outcomes {
+		message := o.message()
+		if in.Explain {
+			message = o.explanation()
+		}
+		if o.status != outcomeIgnored {
+			total++
+		}
+		if o.status == outcomeBlocked {
+			response.Warning(rsp, errors.New(message)).
+				WithReason(string(o.status)).
+				TargetCompositeAndClaim()
+			continue
+		}
+		if o.status == outcomePending {
+			response.Normal(rsp, message).
+				WithReason(string(o.status)).
+				TargetCompositeAndClaim()
+			continue
+		}
+		if o.status != outcomeFailed {
+			response.Normal(rsp, message).
+				WithReason(string(o.status))
+			if o.drift.err != nil {
+				response.Warning(rsp, errors.New(o.driftMessage())).
+					WithReason(reasonDrift)
+			} else if len(o.drift.fields) > 0 {
+				response.Normal(rsp, o.driftMessage()).
+					WithReason(reasonDrift)
+			}
+			if o.unmarked != "" {
+				response.Warning(rsp, errors.New(o.unmarkedMessage())).
+					WithReason(reasonUnmarked)
+			}
+			continue
+		}
+
+		switch policy.ErrorPolicy(in, o.gvk, o.name) {
+		case v1beta1.ErrorPolicyContinue:
+			response.Normal(rsp, message).
+				WithReason(string(o.status))
+		case v1beta1.ErrorPolicyFatal:
+			failures++
+			response.Fatal(rsp, errors.New(message))
+		default:
+			failures++
+			response.Warning(rsp, errors.New(message)).
+				WithReason(string(o.status)).
+				TargetCompositeAndClaim()
+		}
+	}
+
+	if failures > 0 {
+		response.ConditionFalse(rsp, "FunctionSuccess", "ImportFailed").
+			WithMessage(fmt.Sprintf("%d of %d GitLab resources could not be imported", failures, total)).
+			TargetCompositeAndClaim()
+		return
+	}
+
+	// You can set a custom status condition on the claim. This allows you to
+	// communicate with the user. See the link below for status condition
+	// guidance.
+	// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
+	response.ConditionTrue(rsp, "FunctionSuccess", "Success").
+		TargetCompositeAndClaim()
+}
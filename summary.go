@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/simon-fredrich/function-gitlab-importer/internal"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// importSummaryEntry describes an imported composed resource within the
+// import summary on the status of the composite resource.
+type importSummaryEntry struct {
+	Name       string `json:"name"`
+	ID         string `json:"id"`
+	FullPath   string `json:"fullPath,omitempty"`
+	WebURL     string `json:"webUrl,omitempty"`
+	ImportedAt string `json:"importedAt,omitempty"`
+	// Drift are the fields differing from the GitLab resource on import,
+	// if a drift report is requested.
+	Drift []internal.FieldDrift `json:"drift,omitempty"`
+}
+
+// importSummary returns the import summary of all imported composed resources.
+// Resources imported during this request are stamped with now. Details of
+// resources imported earlier are carried over from the observed summary, as
+// they are not looked up again. Resources imported before the summary has been
+// enabled are backfilled from the provenance annotations of the observed
+// composed resources.
+func importSummary(outcomes []outcome, observed []importSummaryEntry, resources map[resource.Name]resource.ObservedComposed, now time.Time) []importSummaryEntry {
+	previous := make(map[string]importSummaryEntry, len(observed))
+	for _, e := range observed {
+		previous[e.Name] = e
+	}
+
+	summary := []importSummaryEntry{}
+	for _, o := range outcomes {
+		e := importSummaryEntry{
+			Name:     string(o.name),
+			ID:       o.result.ExternalName,
+			FullPath: o.result.FullPath,
+			WebURL:   o.result.WebURL,
+		}
+		switch {
+		case o.status == outcomeImported:
+			e.ImportedAt = now.UTC().Format(time.RFC3339)
+			e.Drift = o.drift.fields
+		case o.status == outcomeSkipped && o.result.ExternalName != "":
+			// resources skipped as already managed have been imported before
+			if p, ok := previous[e.Name]; ok && p.ID == e.ID {
+				e = p
+			} else if obs, ok := resources[o.name]; ok {
+				e = backfillSummaryEntry(e, obs)
+			}
+		default:
+			// blocked, pending and failed resources as well as resources
+			// that would have been imported in dry run mode are not adopted,
+			// even if they have been looked up
+			continue
+		}
+		summary = append(summary, e)
+	}
+	return summary
+}
+
+// backfillSummaryEntry fills in the details of an entry from the provenance
+// and drift annotations of the observed composed resource. Annotations that
+// are missing or cannot be parsed are left out.
+func backfillSummaryEntry(e importSummaryEntry, obs resource.ObservedComposed) importSummaryEntry {
+	annotations := obs.Resource.GetAnnotations()
+	e.FullPath = annotations[internal.AnnotationFullPath]
+	e.WebURL = annotations[internal.AnnotationWebURL]
+	e.ImportedAt = annotations[internal.AnnotationImportedAt]
+	if raw, ok := annotations[internal.AnnotationDrift]; ok {
+		drift := []internal.FieldDrift{}
+		if err := json.Unmarshal([]byte(raw), &drift); err == nil && len(drift) > 0 {
+			e.Drift = drift
+		}
+	}
+	return e
+}
+
+// getImportSummary reads the import summary at fieldPath of the observed
+// composite resource. It returns no entries if there is no summary yet.
+func getImportSummary(xr *resource.Composite, fieldPath string) ([]importSummaryEntry, error) {
+	value, err := xr.Resource.GetValue(fieldPath)
+	if fieldpath.IsNotFound(err) {
+		// there is no summary before the first import
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("cannot get import summary at %s: %w", fieldPath, err)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Errorf("cannot marshal import summary: %w", err)
+	}
+	summary := []importSummaryEntry{}
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return nil, errors.Errorf("cannot unmarshal import summary at %s: %w", fieldPath, err)
+	}
+	return summary, nil
+}
+
+// setImportSummary writes the import summary at fieldPath of the desired
+// composite resource. The field path has to point into its status.
+func setImportSummary(xr *resource.Composite, fieldPath string, summary []importSummaryEntry) error {
+	if !strings.HasPrefix(fieldPath, "status.") {
+		return errors.Errorf("import summary field path %q does not point into the status", fieldPath)
+	}
+	if err := xr.Resource.SetValue(fieldPath, summary); err != nil {
+		return errors.Errorf("cannot set import summary at %s: %w", fieldPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/simon-fredrich/function-gitlab-importer/internal"
+	"github.com/simon-fredrich/function-gitlab-importer/internal/importer"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestImportSummary(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "projects.gitlab.crossplane.io", Version: "v1alpha1", Kind: "Project"}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	type args struct {
+		outcomes  []outcome
+		observed  []importSummaryEntry
+		resources map[resource.Name]resource.ObservedComposed
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []importSummaryEntry
+	}{
+		"NewImport": {
+			reason: "Resources imported during this request should be stamped with the current time.",
+			args: args{
+				outcomes: []outcome{
+					imported("project", gvk, importer.Result{ExternalName: "42", FullPath: "team/project", WebURL: "https://gitlab.com/team/project"}),
+				},
+			},
+			want: []importSummaryEntry{
+				{Name: "project", ID: "42", FullPath: "team/project", WebURL: "https://gitlab.com/team/project", ImportedAt: "2026-10-18T12:00:00Z"},
+			},
+		},
+		"RecordDrift": {
+			reason: "The drift of resources imported during this request should be recorded.",
+			args: args{
+				outcomes: []outcome{
+					imported("project", gvk, importer.Result{ExternalName: "42"}).
+						withDrift(driftReport{fields: []internal.FieldDrift{{Field: "visibility", Desired: "private", GitLab: "public"}}}),
+				},
+			},
+			want: []importSummaryEntry{
+				{Name: "project", ID: "42", ImportedAt: "2026-10-18T12:00:00Z", Drift: []internal.FieldDrift{{Field: "visibility", Desired: "private", GitLab: "public"}}},
+			},
+		},
+		"CarryOverEarlierImport": {
+			reason: "Details of resources imported earlier should be carried over from the observed summary.",
+			args: args{
+				outcomes: []outcome{
+					alreadyManaged("project", gvk, "42"),
+				},
+				observed: []importSummaryEntry{
+					{Name: "project", ID: "42", FullPath: "team/project", ImportedAt: "2026-01-01T00:00:00Z"},
+				},
+			},
+			want: []importSummaryEntry{
+				{Name: "project", ID: "42", FullPath: "team/project", ImportedAt: "2026-01-01T00:00:00Z"},
+			},
+		},
+		"IgnoreOutdatedEntry": {
+			reason: "Observed entries of a different external resource should not be carried over.",
+			args: args{
+				outcomes: []outcome{
+					alreadyManaged("project", gvk, "43"),
+				},
+				observed: []importSummaryEntry{
+					{Name: "project", ID: "42", FullPath: "team/project", ImportedAt: "2026-01-01T00:00:00Z"},
+				},
+			},
+			want: []importSummaryEntry{
+				{Name: "project", ID: "43"},
+			},
+		},
+		"BackfillEarlierImport": {
+			reason: "Resources imported before the summary has been enabled should be backfilled from their provenance annotations.",
+			args: args{
+				outcomes: []outcome{
+					alreadyManaged("project", gvk, "42"),
+				},
+				resources: map[resource.Name]resource.ObservedComposed{
+					"project": observedWithAnnotations(map[string]string{
+						internal.AnnotationFullPath:   "team/project",
+						internal.AnnotationWebURL:     "https://gitlab.com/team/project",
+						internal.AnnotationImportedAt: "2026-01-01T00:00:00Z",
+						internal.AnnotationDrift:      `[{"field":"visibility","desired":"private","gitlab":"public"}]`,
+					}),
+				},
+			},
+			want: []importSummaryEntry{
+				{Name: "project", ID: "42", FullPath: "team/project", WebURL: "https://gitlab.com/team/project", ImportedAt: "2026-01-01T00:00:00Z", Drift: []internal.FieldDrift{{Field: "visibility", Desired: "private", GitLab: "public"}}},
+			},
+		},
+		"BackfillOutdatedEntry": {
+			reason: "Observed entries of a different external resource should be replaced by the provenance annotations.",
+			args: args{
+				outcomes: []outcome{
+					alreadyManaged("project", gvk, "43"),
+				},
+				observed: []importSummaryEntry{
+					{Name: "project", ID: "42", FullPath: "team/project", ImportedAt: "2026-01-01T00:00:00Z"},
+				},
+				resources: map[resource.Name]resource.ObservedComposed{
+					"project": observedWithAnnotations(map[string]string{
+						internal.AnnotationFullPath:   "team/renamed",
+						internal.AnnotationImportedAt: "2026-02-01T00:00:00Z",
+						internal.AnnotationDrift:      "not json",
+					}),
+				},
+			},
+			want: []importSummaryEntry{
+				{Name: "project", ID: "43", FullPath: "team/renamed", ImportedAt: "2026-02-01T00:00:00Z"},
+			},
+		},
+		"OmitNotImported": {
+			reason: "Skipped and failed resources should not be part of the summary.",
+			args: args{
+				outcomes: []outcome{
+					skipped("skipped", gvk, "no existing external resource reported"),
+					failed("failed", gvk, errors.New("boom")),
+				},
+			},
+			want: []importSummaryEntry{},
+		},
+		"OmitBlocked": {
+			reason: "Resources whose import has been blocked should not be part of the summary, even if they have been looked up.",
+			args: args{
+				outcomes: []outcome{
+					withResult(blocked("project", gvk, "team/project matches deny pattern fullPath=team/*"), importer.Result{ExternalName: "42", FullPath: "team/project"}),
+				},
+			},
+			want: []importSummaryEntry{},
+		},
+		"OmitPending": {
+			reason: "Resources whose import waits for approval should not be part of the summary, even if they have been looked up.",
+			args: args{
+				outcomes: []outcome{
+					withResult(pendingApproval("project", gvk, "found 43 (team/project)"), importer.Result{ExternalName: "43", FullPath: "team/project"}),
+				},
+			},
+			want: []importSummaryEntry{},
+		},
+		"OmitWouldImport": {
+			reason: "Resources that would have been imported in dry run mode should not be part of the summary.",
+			args: args{
+				outcomes: []outcome{
+					wouldImport("project", gvk, importer.Result{ExternalName: "42", FullPath: "team/project"}, []string{"Observe"}),
+				},
+			},
+			want: []importSummaryEntry{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := importSummary(tc.args.outcomes, tc.args.observed, tc.args.resources, now)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nimportSummary(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// withResult returns the outcome with the given lookup result, like
+// processResources sets it on blocked and pending outcomes.
+func withResult(o outcome, result importer.Result) outcome {
+	o.result = result
+	return o
+}
+
+// observedWithAnnotations returns an observed composed resource carrying the
+// given annotations.
+func observedWithAnnotations(annotations map[string]string) resource.ObservedComposed {
+	obs := resource.ObservedComposed{Resource: composed.New()}
+	obs.Resource.SetAnnotations(annotations)
+	return obs
+}